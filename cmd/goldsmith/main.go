@@ -3,38 +3,51 @@ package main
 import (
 	"context"
 	"fmt"
-	"math"
 	"net/http"
 	_ "net/http/pprof"
 	"os"
-	"path/filepath"
 	"runtime"
 	"runtime/pprof"
 	"runtime/trace"
+	"strings"
+	"text/tabwriter"
 	"time"
 
 	"github.com/brandonpollack23/goldsmith/cmd/goldsmith/ui"
+	"github.com/brandonpollack23/goldsmith/pkg/audio"
 	"github.com/brandonpollack23/goldsmith/pkg/fft"
 	otelsetup "github.com/brandonpollack23/goldsmith/pkg/otel"
 	"github.com/brandonpollack23/goldsmith/pkg/vis"
 	"github.com/gopxl/beep"
-	"github.com/gopxl/beep/mp3"
 	"github.com/gopxl/beep/speaker"
-	"github.com/gopxl/beep/wav"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 	"go.opentelemetry.io/otel"
 )
 
-// TODO display playback bar at the bottom with timestamp and max time etc.
-// TODO Volume with beep
 // TODO other beep effects?
 // TODO animations on bars using harmonica (like progress has)?
-// TODO add CTRL for play/pause
 
 var (
 	targetFPS       uint32
+	fftSize         uint32
+	hopSize         uint32
+	overlap         float64
+	windowFunc      string
 	visType         string
+	visOptions      map[string]string
+	attack          time.Duration
+	release         time.Duration
+	peakGravity     float64
+	shuffle         bool
+	repeat          bool
+	crossfade       time.Duration
+	latencyOffset   time.Duration
+	raw             bool
+	rawRate         int
+	rawChannels     int
+	rawFormat       string
+	rawBigEndian    bool
 	showFPS         bool
 	otelTracing     bool
 	runtimeProfiler bool
@@ -44,38 +57,91 @@ var (
 
 const name = "github.com/brandonpollack23/goldsmith/cmd/goldsmith"
 
+// How much audio the speaker buffers ahead of what is being heard.
+const speakerBuffer = time.Second / 10
+
 var (
 	tracer = otel.Tracer(name)
 )
 
 func main() {
 	rootCmd := &cobra.Command{
-		Use:   "goldsmith [music filename]",
+		Use:   "goldsmith [music files, directories, playlists, http(s) URLs or - for stdin]...",
 		Short: "A cli based music visualizer written in go",
 		Long: `This is a cli application built on bubbletea/bubbles and some go fft libraries 
 and audio libraries to bring you some magic bars for visualization. Maybe one day a gui etc too.`,
-		Args: cobra.ExactArgs(1),
+		Args: cobra.MinimumNArgs(1),
 		// Uncomment the following line if your bare application
 		// has an action associated with it:
 		RunE: runVisualizer,
 	}
 
 	rootCmd.PersistentFlags().Uint32VarP(&targetFPS, "target_fps", "f", 30,
-		"The updates FPS for the visualizer, affects FFT window")
+		"The updates FPS for the visualizer, sets the hop between FFT windows unless --hop or --overlap is given")
+	rootCmd.PersistentFlags().Uint32Var(&fftSize, "fft_size", 2048,
+		"Number of samples per FFT, a power of two. Larger resolves low frequencies better")
+	rootCmd.PersistentFlags().Uint32Var(&hopSize, "hop", 0,
+		"Samples between the starts of consecutive FFT windows, overrides --target_fps")
+	rootCmd.PersistentFlags().Float64Var(&overlap, "overlap", 0,
+		"Fraction in [0, 1) that consecutive FFT windows overlap by, overrides --target_fps")
+	rootCmd.PersistentFlags().StringVar(&windowFunc, "window", "hann",
+		"Window function applied before each FFT: "+strings.Join(fft.WindowFuncNames(), ", "))
 	rootCmd.PersistentFlags().StringVarP(&visType, "visualizer", "v", "vertical_bars",
-		"Which visualizer type to use")
+		"Which visualizer type to use, see list-visualizers")
+	rootCmd.PersistentFlags().StringToStringVar(&visOptions, "vis_opt", nil,
+		"Visualizer specific options as name=value, see list-visualizers")
+	rootCmd.PersistentFlags().DurationVar(&attack, "attack", 10*time.Millisecond,
+		"How quickly bars rise to louder levels, 0 to jump straight there")
+	rootCmd.PersistentFlags().DurationVar(&release, "release", 150*time.Millisecond,
+		"How quickly bars fall back to quieter levels, 0 to drop straight there")
+	rootCmd.PersistentFlags().Float64Var(&peakGravity, "peak_gravity", 2,
+		"How fast peak caps fall, in bar lengths per second squared, 0 to hide them")
 	rootCmd.PersistentFlags().BoolVarP(&showFPS, "showfps", "s", false,
 		"Show FPS below visualizer")
+	rootCmd.PersistentFlags().BoolVar(&shuffle, "shuffle", false,
+		"Play the tracks in a random order")
+	rootCmd.PersistentFlags().BoolVar(&repeat, "repeat", false,
+		"Start over from the first track after the last one")
+	rootCmd.PersistentFlags().DurationVar(&crossfade, "crossfade", 0,
+		"Blend the end of each track into the next over this long, e.g. 5s")
+	rootCmd.PersistentFlags().DurationVar(&latencyOffset, "latency_offset", 0,
+		"Extra audio output delay to hold the visuals back by, e.g. 200ms for Bluetooth headsets")
+	rootCmd.PersistentFlags().BoolVar(&raw, "raw", false,
+		"Treat the inputs as headerless PCM described by the --raw_* flags")
+	rootCmd.PersistentFlags().IntVar(&rawRate, "raw_rate", 44100,
+		"Sample rate of raw input")
+	rootCmd.PersistentFlags().IntVar(&rawChannels, "raw_channels", 2,
+		"Number of interleaved channels in raw input")
+	rootCmd.PersistentFlags().StringVar(&rawFormat, "raw_format", "s16",
+		"Sample format of raw input: u8, s16, s24, s32, f32 or f64")
+	rootCmd.PersistentFlags().BoolVar(&rawBigEndian, "raw_big_endian", false,
+		"Raw input is big endian instead of little endian")
 
 	err := rootCmd.RegisterFlagCompletionFunc("visualizer", func(cmd *cobra.Command, args []string,
 		toComplete string,
 	) ([]string, cobra.ShellCompDirective) {
-		return []string{"horizontal_bars", "vertical_bars"}, cobra.ShellCompDirectiveNoFileComp
+		return vis.Names(), cobra.ShellCompDirectiveNoFileComp
 	})
 	if err != nil {
 		panic(err)
 	}
 
+	err = rootCmd.RegisterFlagCompletionFunc("window", func(cmd *cobra.Command, args []string,
+		toComplete string,
+	) ([]string, cobra.ShellCompDirective) {
+		return fft.WindowFuncNames(), cobra.ShellCompDirectiveNoFileComp
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	rootCmd.AddCommand(&cobra.Command{
+		Use:   "list-visualizers",
+		Short: "List the available visualizers and their options",
+		Args:  cobra.NoArgs,
+		Run:   listVisualizers,
+	})
+
 	rootCmd.PersistentFlags().BoolVarP(&otelTracing, "otel", "o", false, "Enable otel tracing")
 	rootCmd.PersistentFlags().BoolVarP(&runtimeProfiler, "runtimeProfile", "r", false,
 		"Enable runtime profiler available at port 8080")
@@ -86,7 +152,8 @@ and audio libraries to bring you some magic bars for visualization. Maybe one da
 
 	err = rootCmd.Execute()
 	if err != nil {
-		panic("Fatal error: " + err.Error())
+		// Cobra has already printed the error.
+		os.Exit(1)
 	}
 }
 
@@ -128,52 +195,66 @@ func runVisualizer(cmd *cobra.Command, args []string) error {
 	ctx, trace := tracer.Start(ctx, "main")
 	defer trace.End()
 
-	audioFile, err := os.Open(args[0])
+	tracks, err := inputTracks(args)
 	if err != nil {
-		return fmt.Errorf("error opening file: %w", err)
+		return err
 	}
-	defer audioFile.Close()
 
-	streamer, format, err := decodeAudioFile(audioFile)
+	queue, err := audio.NewQueue(tracks,
+		audio.WithShuffle(shuffle), audio.WithRepeat(repeat), audio.WithCrossfade(crossfade))
 	if err != nil {
-		return fmt.Errorf("error decoding file %s: %w", audioFile.Name(), err)
+		return err
 	}
-	defer streamer.Close()
-
-	windowDuration := time.Duration(float64(time.Second) / float64(targetFPS))
-	fftWindowSize := uint32(format.SampleRate.N(windowDuration))
-	fftStreamer := fft.NewFFTStreamer(ctx, streamer, fftWindowSize, format)
-	songDuration := format.SampleRate.D(streamer.Len())
+	defer func() {
+		// The speaker streams from the queue on its own goroutine, stop it
+		// before closing the tracks from under it.
+		speaker.Clear()
+		queue.Close()
+	}()
+	format := queue.Format()
+
+	channelSpectra, err := vis.NeedsChannelSpectra(visType, format, visOptions)
+	if err != nil {
+		return err
+	}
+	fftOpts, hop, err := analysisOptions(format, channelSpectra)
+	if err != nil {
+		return err
+	}
+	windowDuration := format.SampleRate.D(int(hop))
+	outputLatency := speakerBuffer + latencyOffset
+	fftOpts = append(fftOpts, fft.WithOutputLatency(outputLatency))
+	player := audio.NewPlayer(ctx, queue, hop, fftOpts...)
 
-	// Initialize the speaker to use the sample rate of the audio file selected.
+	// Initialize the speaker to use the sample rate of the first track.
 	// I can also use beep.Resample around the streamer to always use a specific
 	// output sample rate for everything no matter the input.
 	ctx, trace = tracer.Start(ctx, "main.speakerinit")
-	err = speaker.Init(format.SampleRate, format.SampleRate.N(time.Second/10))
+	err = speaker.Init(format.SampleRate, format.SampleRate.N(speakerBuffer))
 	trace.End()
 	if err != nil {
 		return fmt.Errorf("cannot initializer speaker: %w", err)
 	}
 
-	var visualizer vis.Visualizer
-	switch visType {
-	case "horizontal_bars":
-		visualizer = vis.NewHorizontalBarsVisualizer(32,
-			int(math.Pow(2, float64(8*format.Precision))), vis.WithFPS(showFPS))
-	case "vertical_bars":
-		visualizer = vis.NewVerticalBarsVisualizer(64, 40, vis.WithFPS(showFPS))
-	default:
-		panic("unknown visualizer type: " + visType)
+	visOpts := []vis.VisualizerOption{
+		vis.WithFPS(showFPS),
+		vis.WithPlayback(player),
+		vis.WithSmoothing(attack, release),
+		vis.WithPeakCaps(peakGravity),
 	}
 
-	ctx = context.WithValue(ctx, ui.FFTDeadlineKey, 6*windowDuration)
-	ctx, cancel := context.WithTimeout(ctx, songDuration+5*time.Second)
-	defer cancel()
+	visualizer, err := vis.New(visType, format, visOptions, visOpts...)
+	if err != nil {
+		return err
+	}
+
+	// Windows are held back until they are heard, so allow for that on top.
+	ctx = context.WithValue(ctx, ui.FFTDeadlineKey, 6*windowDuration+outputLatency)
 
-	speaker.Play(&fftStreamer)
+	speaker.Play(player.Streamer())
 
 	ctx, trace = tracer.Start(ctx, "updateLoop")
-	err = ui.UpdateLoop(ctx, &fftStreamer, visualizer)
+	err = ui.UpdateLoop(ctx, player.FFTStreamer(), visualizer)
 	trace.End()
 	if err != nil {
 		return fmt.Errorf("update loop exited with error %w", err)
@@ -182,23 +263,83 @@ func runVisualizer(cmd *cobra.Command, args []string) error {
 	return err
 }
 
-func decodeAudioFile(audioFile *os.File) (beep.StreamSeekCloser, beep.Format, error) {
-	var streamer beep.StreamSeekCloser
-	var format beep.Format
-	var err error
+// Works out the FFT settings from the flags, returning them with the hop size.
+// channelSpectra is whether the visualizer draws the per-channel spectra.
+func analysisOptions(format beep.Format, channelSpectra bool) ([]fft.FFTStreamerOption, uint32, error) {
+	if fftSize == 0 || fftSize&(fftSize-1) != 0 {
+		return nil, 0, fmt.Errorf("--fft_size must be a power of two, got %d", fftSize)
+	}
+	if overlap < 0 || overlap >= 1 {
+		return nil, 0, fmt.Errorf("--overlap must be in [0, 1), got %v", overlap)
+	}
+
+	w, ok := fft.LookupWindowFunc(windowFunc)
+	if !ok {
+		return nil, 0, fmt.Errorf("unknown window %q, available windows are: %s",
+			windowFunc, strings.Join(fft.WindowFuncNames(), ", "))
+	}
 
-	extension := filepath.Ext(audioFile.Name())
-	switch extension {
-	case ".mp3":
-		streamer, format, err = mp3.Decode(audioFile)
-	case ".wav":
-		streamer, format, err = wav.Decode(audioFile)
-		// TODO other formats (flac, vorbis, midi, etc)
+	hop := hopSize
+	switch {
+	case hop != 0:
+	case overlap != 0:
+		hop = uint32(float64(fftSize) * (1 - overlap))
+	case targetFPS == 0:
+		return nil, 0, errors.New("--target_fps must be positive")
 	default:
-		return nil, beep.Format{}, fmt.Errorf("unsupported audio file format: %v", extension)
+		hop = uint32(format.SampleRate.N(time.Second / time.Duration(targetFPS)))
+	}
+	hop = max(hop, 1)
+
+	return []fft.FFTStreamerOption{
+		fft.WithFFTSize(fftSize),
+		fft.WithWindowFunc(w),
+		// Mono audio has nothing to tell apart.
+		fft.WithChannelSpectra(channelSpectra && format.NumChannels > 1),
+	}, hop, nil
+}
+
+func listVisualizers(cmd *cobra.Command, args []string) {
+	w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+	defer w.Flush()
+
+	for _, r := range vis.Registered() {
+		fmt.Fprintf(w, "%s\t%s\n", r.Name, r.Description)
+		for _, o := range r.Options {
+			fmt.Fprintf(w, "  %s\t%s (default %s)\n", o.Name, o.Description, o.Default)
+		}
+	}
+}
+
+// Raw input is taken as given, everything else can be directories and
+// playlists.
+func inputTracks(args []string) ([]audio.Track, error) {
+	if !raw {
+		paths, err := audio.ExpandPaths(args)
+		if err != nil {
+			return nil, fmt.Errorf("error reading arguments: %w", err)
+		}
+
+		return audio.FileTracks(paths), nil
+	}
+
+	sampleFormat, err := audio.ParseSampleFormat(rawFormat)
+	if err != nil {
+		return nil, err
+	}
+	format := audio.RawFormat{
+		SampleRate:   beep.SampleRate(rawRate),
+		NumChannels:  rawChannels,
+		SampleFormat: sampleFormat,
+		BigEndian:    rawBigEndian,
+	}
+
+	tracks := make([]audio.Track, len(args))
+	for i, path := range args {
+		tracks[i] = audio.RawTrack(path, format)
 	}
 
-	return streamer, format, err
+	return tracks, nil
 }
 
 // Initializes profiling and returns a function to defer to stop it.
@@ -2,6 +2,7 @@ package ui
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
@@ -16,6 +17,9 @@ var (
 	tracer = otel.Tracer(name)
 )
 
+// How long the visualizer gets to shut down once the audio has finished.
+const visualizerExitTimeout = 5 * time.Second
+
 type UiKeyType int
 
 const (
@@ -60,12 +64,44 @@ func UpdateLoop(ctx context.Context, s FFTStreamer, visualizer vis.Visualizer) e
 			cancel()
 
 			if err != nil {
+				// A source stalled waiting on data (a pipe, the network) just
+				// skips frames, it should not end playback.
+				if errors.Is(fftCtx.Err(), context.DeadlineExceeded) && ctx.Err() == nil {
+					trace.End()
+					continue
+				}
 				return err
 			}
 
-			visualizer.UpdateVisualizer(vis.NewFFTData{Data: nextFFTWindow.Data, Done: !ok})
+			visualizer.UpdateVisualizer(vis.NewFFTData{
+				Data:    nextFFTWindow.Data,
+				Left:    nextFFTWindow.Left,
+				Right:   nextFFTWindow.Right,
+				Side:    nextFFTWindow.Side,
+				Samples: nextFFTWindow.Samples,
+				Levels:  nextFFTWindow.Levels,
+				Gain:    nextFFTWindow.Gain,
+				Offset:  nextFFTWindow.Offset,
+				Done:    !ok,
+			})
+			if !ok {
+				trace.End()
+				return waitForExit(exitChan)
+			}
 		}
 
 		trace.End()
 	}
 }
+
+// The audio is done, so the visualizer should be on its way out. Playback
+// length is not known up front (pauses, seeks), so this is the only place a
+// visualizer outliving its audio can be caught.
+func waitForExit(exitChan <-chan error) error {
+	select {
+	case err := <-exitChan:
+		return err
+	case <-time.After(visualizerExitTimeout):
+		return errors.New("visualizer somehow running longer than audio file")
+	}
+}
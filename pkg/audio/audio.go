@@ -0,0 +1,239 @@
+package audio
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/gopxl/beep"
+)
+
+// DecodeFunc turns an audio stream into a beep streamer and its format.
+type DecodeFunc func(io.ReadCloser) (beep.StreamSeekCloser, beep.Format, error)
+
+// A format is a registered decoder together with the ways to recognize it.
+type format struct {
+	name       string
+	magic      []string
+	extensions []string
+	decode     DecodeFunc
+}
+
+var (
+	formatsMu sync.Mutex
+	formats   []format
+)
+
+var (
+	// ErrUnknownFormat is returned when neither the content nor the file name
+	// matches a registered format.
+	ErrUnknownFormat = errors.New("audio: unknown format")
+	// ErrNotSeekable is returned when seeking a stream that was decoded from a
+	// pipe or similar.
+	ErrNotSeekable = errors.New("audio: stream is not seekable")
+)
+
+// Register makes a decoder available to [Decode].
+//
+// Each magic string is matched against the start of the stream, with '?'
+// matching any single byte (the same convention as image.RegisterFormat).
+// Extensions (including the leading dot) are only consulted when no magic
+// string matches, so mislabeled files still decode.
+func Register(name string, magic []string, extensions []string, decode DecodeFunc) {
+	formatsMu.Lock()
+	defer formatsMu.Unlock()
+
+	exts := make([]string, len(extensions))
+	for i, e := range extensions {
+		exts[i] = strings.ToLower(e)
+	}
+
+	formats = append(formats, format{
+		name:       name,
+		magic:      magic,
+		extensions: exts,
+		decode:     decode,
+	})
+}
+
+// Formats returns the names of all registered formats in registration order.
+func Formats() []string {
+	formatsMu.Lock()
+	defer formatsMu.Unlock()
+
+	names := make([]string, len(formats))
+	for i, f := range formats {
+		names[i] = f.name
+	}
+
+	return names
+}
+
+// Decode picks a registered decoder by sniffing the header of r, falling back
+// to the extension of name, and decodes r with it. The returned streamer owns
+// r and closes it.
+//
+// r does not need to be seekable (stdin, FIFOs, network streams), but then the
+// returned streamer cannot seek either and reports a Len of 0, which is how an
+// unknown length is signalled throughout this package.
+func Decode(r io.ReadCloser, name string) (beep.StreamSeekCloser, beep.Format, error) {
+	if rs, ok := r.(io.ReadSeekCloser); ok && seekable(rs) {
+		return decodeSeekable(rs, name)
+	}
+
+	return decodeStream(r, name)
+}
+
+func decodeSeekable(r io.ReadSeekCloser, name string) (beep.StreamSeekCloser, beep.Format, error) {
+	header := make([]byte, maxMagicLen())
+	n, err := io.ReadFull(r, header)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return nil, beep.Format{}, fmt.Errorf("error reading header: %w", err)
+	}
+	header = header[:n]
+
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, beep.Format{}, fmt.Errorf("error rewinding stream: %w", err)
+	}
+
+	return decodeWith(header, r, name)
+}
+
+// Peeks at the header instead of rewinding, and hides the decoder's seeking
+// since it would fail anyway.
+func decodeStream(r io.ReadCloser, name string) (beep.StreamSeekCloser, beep.Format, error) {
+	br := bufio.NewReader(r)
+	header, err := br.Peek(maxMagicLen())
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, beep.Format{}, fmt.Errorf("error reading header: %w", err)
+	}
+
+	streamer, format, err := decodeWith(header, readCloser{Reader: br, Closer: r}, name)
+	if err != nil {
+		return nil, beep.Format{}, err
+	}
+
+	return &unseekable{StreamSeekCloser: streamer}, format, nil
+}
+
+func decodeWith(header []byte, r io.ReadCloser, name string) (beep.StreamSeekCloser, beep.Format, error) {
+	f, ok := sniff(header, name)
+	if !ok {
+		return nil, beep.Format{}, fmt.Errorf("%w: %s", ErrUnknownFormat, name)
+	}
+
+	streamer, format, err := f.decode(r)
+	if err != nil {
+		return nil, beep.Format{}, fmt.Errorf("error decoding %s as %s: %w", name, f.name, err)
+	}
+
+	return streamer, format, nil
+}
+
+// Files implement io.Seeker even when they are pipes, so actually try it.
+func seekable(s io.Seeker) bool {
+	_, err := s.Seek(0, io.SeekCurrent)
+	return err == nil
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// Wraps the decoder of a stream that cannot seek, counting the position
+// itself since the decoders' lengths are meaningless without seeking.
+type unseekable struct {
+	beep.StreamSeekCloser
+	pos int
+}
+
+func (u *unseekable) Stream(samples [][2]float64) (int, bool) {
+	n, ok := u.StreamSeekCloser.Stream(samples)
+	u.pos += n
+	return n, ok
+}
+
+func (u *unseekable) Len() int {
+	return 0
+}
+
+func (u *unseekable) Position() int {
+	return u.pos
+}
+
+func (u *unseekable) Seek(int) error {
+	return ErrNotSeekable
+}
+
+func sniff(header []byte, name string) (format, bool) {
+	formatsMu.Lock()
+	defer formatsMu.Unlock()
+
+	for _, f := range formats {
+		for _, m := range f.magic {
+			if match(m, header) {
+				return f, true
+			}
+		}
+	}
+
+	ext := strings.ToLower(filepath.Ext(name))
+	for _, f := range formats {
+		for _, e := range f.extensions {
+			if e == ext {
+				return f, true
+			}
+		}
+	}
+
+	return format{}, false
+}
+
+func supportedExtension(ext string) bool {
+	formatsMu.Lock()
+	defer formatsMu.Unlock()
+
+	ext = strings.ToLower(ext)
+	for _, f := range formats {
+		for _, e := range f.extensions {
+			if e == ext {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func match(magic string, b []byte) bool {
+	if len(magic) > len(b) {
+		return false
+	}
+
+	for i, c := range []byte(magic) {
+		if c != b[i] && c != '?' {
+			return false
+		}
+	}
+
+	return true
+}
+
+func maxMagicLen() int {
+	formatsMu.Lock()
+	defer formatsMu.Unlock()
+
+	n := 0
+	for _, f := range formats {
+		for _, m := range f.magic {
+			n = max(n, len(m))
+		}
+	}
+
+	return n
+}
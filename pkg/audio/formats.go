@@ -0,0 +1,44 @@
+package audio
+
+import (
+	"io"
+
+	"github.com/gopxl/beep"
+	"github.com/gopxl/beep/flac"
+	"github.com/gopxl/beep/mp3"
+	"github.com/gopxl/beep/vorbis"
+	"github.com/gopxl/beep/wav"
+)
+
+// Built in formats, everything beep ships a decoder for.
+func init() {
+	Register("flac", []string{"fLaC"}, []string{".flac"},
+		func(r io.ReadCloser) (beep.StreamSeekCloser, beep.Format, error) {
+			return flac.Decode(r)
+		})
+	// TODO Ogg can hold other codecs too (opus, flac), for now assume vorbis.
+	Register("vorbis", []string{"OggS"}, []string{".ogg", ".oga"}, vorbis.Decode)
+	Register("wav", []string{"RIFF????WAVE"}, []string{".wav"},
+		func(r io.ReadCloser) (beep.StreamSeekCloser, beep.Format, error) {
+			return wav.Decode(r)
+		})
+	// ID3 tag or a bare MPEG audio frame sync.
+	Register("mp3", append([]string{"ID3"}, frameSyncs()...), []string{".mp3"}, mp3.Decode)
+	// TODO other formats (midi, etc)
+}
+
+// Every start of an MPEG audio frame: 11 set bits of sync followed by the
+// version, layer and protection bits. The reserved version and layer are left
+// out, the latter being what ADTS AAC has there instead.
+func frameSyncs() []string {
+	var syncs []string
+	for b := 0xE0; b <= 0xFF; b++ {
+		version, layer := b>>3&0b11, b>>1&0b11
+		if version == 0b01 || layer == 0b00 {
+			continue
+		}
+		syncs = append(syncs, string([]byte{0xFF, byte(b)}))
+	}
+
+	return syncs
+}
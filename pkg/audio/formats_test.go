@@ -0,0 +1,64 @@
+package audio
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestSniff(t *testing.T) {
+	tests := []struct {
+		header string
+		name   string
+		format string
+	}{
+		{"fLaC\x00\x00", "song", "flac"},
+		{"OggS\x00\x02", "song", "vorbis"},
+		{"RIFF\x24\x00\x00\x00WAVE", "song", "wav"},
+		{"ID3\x04\x00", "song", "mp3"},
+		// MPEG-1, 2 and 2.5 layer III frame syncs, with and without CRC.
+		{"\xff\xfb\x90\x00", "song", "mp3"},
+		{"\xff\xfa\x90\x00", "song", "mp3"},
+		{"\xff\xf3\x90\x00", "song", "mp3"},
+		{"\xff\xf2\x90\x00", "song", "mp3"},
+		{"\xff\xe3\x90\x00", "song", "mp3"},
+		{"\xff\xe2\x90\x00", "song", "mp3"},
+		// Layers I and II.
+		{"\xff\xfd\x90\x00", "song", "mp3"},
+		{"\xff\xfe\x90\x00", "song", "mp3"},
+		// Content wins over a wrong extension.
+		{"\xff\xe3\x90\x00", "song.flac", "mp3"},
+		{"fLaC\x00\x00", "song.mp3", "flac"},
+		// The extension is the fallback.
+		{"\x00\x00\x00\x00", "song.ogg", "vorbis"},
+		{"\xff\xd0\x00\x00", "song.wav", "wav"},
+	}
+
+	for _, tt := range tests {
+		f, ok := sniff([]byte(tt.header), tt.name)
+		if !ok || f.name != tt.format {
+			t.Errorf("sniff(%q, %q) = %q, %v, want %q", tt.header, tt.name, f.name, ok, tt.format)
+		}
+	}
+
+	for _, header := range []string{
+		"\x00\x00\x00\x00",
+		// ADTS AAC, with and without CRC.
+		"\xff\xf1\x50\x80",
+		"\xff\xf9\x50\x80",
+		// The reserved MPEG version.
+		"\xff\xeb\x90\x00",
+	} {
+		if f, ok := sniff([]byte(header), "song"); ok {
+			t.Errorf("sniffed %q as %q", header, f.name)
+		}
+	}
+}
+
+func TestDecodeADTSIsUnknown(t *testing.T) {
+	adts := "\xff\xf1\x50\x80\x02\x1f\xfc" + strings.Repeat("\x00", 64)
+	if _, _, err := Decode(io.NopCloser(strings.NewReader(adts)), "stream"); !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("err = %v, want %v", err, ErrUnknownFormat)
+	}
+}
@@ -0,0 +1,183 @@
+package audio
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/gopxl/beep"
+)
+
+// Titler is implemented by streams whose title changes as they play, like
+// internet radio announcing the current song.
+type Titler interface {
+	Title() string
+}
+
+// Extensions to fall back on when a stream's header is not recognized.
+var contentTypeExtensions = map[string]string{
+	"audio/mpeg":      ".mp3",
+	"audio/mp3":       ".mp3",
+	"audio/ogg":       ".ogg",
+	"application/ogg": ".ogg",
+	"audio/vorbis":    ".ogg",
+	"audio/flac":      ".flac",
+	"audio/x-flac":    ".flac",
+	"audio/wav":       ".wav",
+	"audio/x-wav":     ".wav",
+}
+
+func isURL(p string) bool {
+	return strings.HasPrefix(p, "http://") || strings.HasPrefix(p, "https://")
+}
+
+// HTTPTrack is a track streamed from an http(s) URL, such as an Icecast or
+// Shoutcast station. Audio is decoded as it arrives, and if the server sends
+// ICY metadata the stream's title follows the song being played.
+func HTTPTrack(rawURL string) Track {
+	name := rawURL
+	if u, err := url.Parse(rawURL); err == nil {
+		name = u.Host + u.Path
+	}
+
+	return Track{
+		Name: name,
+		Live: true,
+		Open: func() (beep.StreamSeekCloser, beep.Format, error) {
+			return openHTTP(rawURL)
+		},
+	}
+}
+
+func openHTTP(rawURL string) (beep.StreamSeekCloser, beep.Format, error) {
+	req, err := http.NewRequest(http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, beep.Format{}, err
+	}
+	req.Header.Set("Icy-MetaData", "1")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, beep.Format{}, fmt.Errorf("error requesting %s: %w", rawURL, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, beep.Format{}, fmt.Errorf("error requesting %s: %s", rawURL, resp.Status)
+	}
+
+	icy := &icyReader{r: resp.Body}
+	icy.metaint, _ = strconv.Atoi(resp.Header.Get("icy-metaint"))
+	icy.untilMeta = icy.metaint
+	if station := resp.Header.Get("icy-name"); station != "" {
+		icy.title.Store(&station)
+	}
+
+	// The URL path rarely has an extension for radio, so name the stream
+	// after its content type in case sniffing fails.
+	name := path.Base(req.URL.Path)
+	if mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type")); err == nil {
+		if ext, ok := contentTypeExtensions[mediaType]; ok {
+			name += ext
+		}
+	}
+
+	s, format, err := Decode(icy, name)
+	if err != nil {
+		icy.Close()
+		return nil, beep.Format{}, err
+	}
+
+	return &titledStream{StreamSeekCloser: s, icy: icy}, format, nil
+}
+
+type titledStream struct {
+	beep.StreamSeekCloser
+	icy *icyReader
+}
+
+func (t *titledStream) Title() string {
+	return t.icy.Title()
+}
+
+// Strips the metadata blocks that ICY servers interleave with the audio every
+// metaint bytes, keeping the latest StreamTitle.
+type icyReader struct {
+	r io.ReadCloser
+	// Zero when the server does not send metadata.
+	metaint   int
+	untilMeta int
+	title     atomic.Pointer[string]
+}
+
+func (r *icyReader) Read(p []byte) (int, error) {
+	if r.metaint == 0 {
+		return r.r.Read(p)
+	}
+
+	if r.untilMeta == 0 {
+		if err := r.readMeta(); err != nil {
+			return 0, err
+		}
+		r.untilMeta = r.metaint
+	}
+
+	n, err := r.r.Read(p[:min(len(p), r.untilMeta)])
+	r.untilMeta -= n
+
+	return n, err
+}
+
+func (r *icyReader) Close() error {
+	return r.r.Close()
+}
+
+func (r *icyReader) Title() string {
+	if t := r.title.Load(); t != nil {
+		return *t
+	}
+
+	return ""
+}
+
+// A metadata block is a length byte (in units of 16 bytes) followed by
+// NUL padded fields like "StreamTitle='Artist - Song';", or nothing at all if
+// the length is 0.
+func (r *icyReader) readMeta() error {
+	var length [1]byte
+	if _, err := io.ReadFull(r.r, length[:]); err != nil {
+		return err
+	}
+
+	meta := make([]byte, int(length[0])*16)
+	if _, err := io.ReadFull(r.r, meta); err != nil {
+		return err
+	}
+
+	if title, ok := parseStreamTitle(string(meta)); ok {
+		r.title.Store(&title)
+	}
+
+	return nil
+}
+
+func parseStreamTitle(meta string) (string, bool) {
+	const key = "StreamTitle='"
+
+	_, rest, ok := strings.Cut(strings.TrimRight(meta, "\x00"), key)
+	if !ok {
+		return "", false
+	}
+
+	title, _, ok := strings.Cut(rest, "';")
+	if !ok {
+		title = strings.TrimSuffix(rest, "'")
+	}
+
+	return title, title != ""
+}
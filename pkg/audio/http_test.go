@@ -0,0 +1,317 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/gopxl/beep"
+)
+
+// Metadata blocks sent in turn, including an empty one that must leave the
+// title alone.
+var testMeta = []string{
+	"StreamTitle='First';",
+	"",
+	"StreamTitle='Second';StreamUrl='';",
+}
+
+// A 16 bit stereo WAV file of n frames of a ramp in each channel.
+func testWAV(n int) []byte {
+	var data bytes.Buffer
+	for i := range n {
+		binary.Write(&data, binary.LittleEndian, [2]int16{int16(i * 37), int16(-i * 53)})
+	}
+
+	header := struct {
+		Riff          [4]byte
+		RiffSize      uint32
+		Wave, Fmt     [4]byte
+		FmtSize       uint32
+		FormatType    uint16
+		NumChans      uint16
+		SampleRate    uint32
+		ByteRate      uint32
+		BytesPerFrame uint16
+		BitsPerSample uint16
+		Data          [4]byte
+		DataSize      uint32
+	}{
+		Riff:          [4]byte{'R', 'I', 'F', 'F'},
+		RiffSize:      uint32(36 + data.Len()),
+		Wave:          [4]byte{'W', 'A', 'V', 'E'},
+		Fmt:           [4]byte{'f', 'm', 't', ' '},
+		FmtSize:       16,
+		FormatType:    1,
+		NumChans:      2,
+		SampleRate:    44100,
+		ByteRate:      44100 * 4,
+		BytesPerFrame: 4,
+		BitsPerSample: 16,
+		Data:          [4]byte{'d', 'a', 't', 'a'},
+		DataSize:      uint32(data.Len()),
+	}
+
+	var b bytes.Buffer
+	binary.Write(&b, binary.LittleEndian, header)
+	b.Write(data.Bytes())
+
+	return b.Bytes()
+}
+
+// Interleaves audio with the blocks of testMeta in turn every metaint bytes,
+// the way an ICY server does.
+func icyBody(audio []byte, metaint int) []byte {
+	var b bytes.Buffer
+	for i := 0; len(audio) > 0; i++ {
+		n := min(metaint, len(audio))
+		b.Write(audio[:n])
+		audio = audio[n:]
+		if n < metaint {
+			break
+		}
+
+		meta := testMeta[i%len(testMeta)]
+		length := (len(meta) + 15) / 16
+		b.WriteByte(byte(length))
+		b.WriteString(meta)
+		b.Write(make([]byte, length*16-len(meta)))
+	}
+
+	return b.Bytes()
+}
+
+func streamAll(t *testing.T, s beep.Streamer) [][2]float64 {
+	t.Helper()
+
+	var all [][2]float64
+	buf := make([][2]float64, 100)
+	for {
+		n, ok := s.Stream(buf)
+		all = append(all, buf[:n]...)
+		if !ok {
+			return all
+		}
+	}
+}
+
+func TestICYReaderStripsMetadata(t *testing.T) {
+	audio := testWAV(500)
+	const metaint = 333
+
+	for name, wrap := range map[string]func(io.Reader) io.Reader{
+		"whole":    func(r io.Reader) io.Reader { return r },
+		"one byte": iotest.OneByteReader,
+		"half":     iotest.HalfReader,
+	} {
+		t.Run(name, func(t *testing.T) {
+			r := &icyReader{
+				r:         io.NopCloser(wrap(bytes.NewReader(icyBody(audio, metaint)))),
+				metaint:   metaint,
+				untilMeta: metaint,
+			}
+
+			// Reads of 100 bytes never line up with the blocks, so some end
+			// right before one and the block is read at the start of the next.
+			var got []byte
+			var titles []string
+			buf := make([]byte, 100)
+			for {
+				n, err := r.Read(buf)
+				got = append(got, buf[:n]...)
+				if title := r.Title(); len(titles) == 0 || titles[len(titles)-1] != title {
+					titles = append(titles, title)
+				}
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if !bytes.Equal(got, audio) {
+				t.Errorf("got %d bytes of audio differing from the %d sent", len(got), len(audio))
+			}
+			// Six blocks, the empty ones keeping the title before them.
+			want := []string{"", "First", "Second", "First", "Second"}
+			if !slices.Equal(titles, want) {
+				t.Errorf("titles = %q, want %q", titles, want)
+			}
+		})
+	}
+}
+
+func TestParseStreamTitle(t *testing.T) {
+	tests := []struct {
+		meta  string
+		title string
+		ok    bool
+	}{
+		{"StreamTitle='Artist - Song';StreamUrl='';\x00\x00", "Artist - Song", true},
+		{"StreamTitle='It's';\x00", "It's", true},
+		{"StreamTitle='Unterminated'\x00\x00", "Unterminated", true},
+		{"StreamTitle='';", "", false},
+		{"StreamUrl='http://example.com';", "", false},
+		{"", "", false},
+	}
+
+	for _, tt := range tests {
+		title, ok := parseStreamTitle(tt.meta)
+		if title != tt.title || ok != tt.ok {
+			t.Errorf("parseStreamTitle(%q) = %q, %v, want %q, %v", tt.meta, title, ok, tt.title, tt.ok)
+		}
+	}
+}
+
+func TestHTTPTrack(t *testing.T) {
+	audio := testWAV(2000)
+	const metaint = 1000
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Icy-MetaData") != "1" {
+			t.Error("metadata not requested")
+		}
+		w.Header().Set("Content-Type", "audio/wav")
+		w.Header().Set("icy-metaint", strconv.Itoa(metaint))
+		w.Header().Set("icy-name", "Station")
+
+		// Odd sized writes, flushed, so metadata blocks arrive split.
+		body := icyBody(audio, metaint)
+		for len(body) > 0 {
+			n := min(777, len(body))
+			w.Write(body[:n])
+			w.(http.Flusher).Flush()
+			body = body[n:]
+		}
+	}))
+	defer server.Close()
+
+	s, format, err := HTTPTrack(server.URL + "/stream").Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	want, wantFormat, err := Decode(io.NopCloser(bytes.NewReader(audio)), "want.wav")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if format != wantFormat {
+		t.Errorf("format = %+v, want %+v", format, wantFormat)
+	}
+
+	got := streamAll(t, s)
+	if !slices.Equal(got, streamAll(t, want)) {
+		t.Errorf("decoded %d frames differing from the %d sent", len(got), 2000)
+	}
+	// Eight blocks, the last of them empty.
+	if title := s.(Titler).Title(); title != "First" {
+		t.Errorf("title at the end = %q, want the last one sent", title)
+	}
+}
+
+func TestHTTPTrackStatus(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	if _, _, err := HTTPTrack(server.URL + "/stream.mp3").Open(); err == nil {
+		t.Error("opened a stream answering 404")
+	}
+}
+
+// Content that no magic matches, so only the content type or the URL can
+// pick the decoder.
+const testFormatContent = "no header to sniff"
+
+func init() {
+	contentTypeExtensions["audio/x-goldsmith-test"] = ".gstest"
+	Register("test", nil, []string{".gstest"},
+		func(r io.ReadCloser) (beep.StreamSeekCloser, beep.Format, error) {
+			return &testFormatStream{r: r}, beep.Format{SampleRate: 1234, NumChannels: 1, Precision: 1}, nil
+		})
+}
+
+type testFormatStream struct {
+	r io.ReadCloser
+}
+
+func (s *testFormatStream) Stream([][2]float64) (int, bool) { return 0, false }
+func (s *testFormatStream) Err() error                      { return nil }
+func (s *testFormatStream) Len() int                        { return 0 }
+func (s *testFormatStream) Position() int                   { return 0 }
+func (s *testFormatStream) Seek(int) error                  { return nil }
+func (s *testFormatStream) Close() error                    { return s.r.Close() }
+
+func TestHTTPTrackChoosesDecoder(t *testing.T) {
+	tests := []struct {
+		name        string
+		path        string
+		contentType string
+		ok          bool
+	}{
+		{"content type", "/stream", "audio/x-goldsmith-test; charset=binary", true},
+		{"extension", "/stream.gstest", "application/octet-stream", true},
+		{"neither", "/stream", "application/octet-stream", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", tt.contentType)
+				io.WriteString(w, testFormatContent)
+			}))
+			defer server.Close()
+
+			s, format, err := HTTPTrack(server.URL + tt.path).Open()
+			if !tt.ok {
+				if !errors.Is(err, ErrUnknownFormat) {
+					t.Errorf("err = %v, want %v", err, ErrUnknownFormat)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer s.Close()
+
+			if format.SampleRate != 1234 {
+				t.Errorf("decoded as %+v, not with the test format", format)
+			}
+		})
+	}
+}
+
+func TestHTTPTrackClosesUndecodable(t *testing.T) {
+	closed := make(chan bool, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		io.WriteString(w, "<html>not audio</html>")
+		w.(http.Flusher).Flush()
+
+		// Keep the stream open like a radio station would, until the client
+		// hangs up.
+		select {
+		case <-r.Context().Done():
+			closed <- true
+		case <-time.After(5 * time.Second):
+			closed <- false
+		}
+	}))
+	defer server.Close()
+
+	if _, _, err := HTTPTrack(server.URL + "/stream").Open(); !errors.Is(err, ErrUnknownFormat) {
+		t.Fatalf("err = %v, want %v", err, ErrUnknownFormat)
+	}
+	if !<-closed {
+		t.Error("connection left open after failing to decode the stream")
+	}
+}
@@ -0,0 +1,161 @@
+package audio
+
+import (
+	"context"
+	"time"
+
+	"github.com/brandonpollack23/goldsmith/pkg/fft"
+	"github.com/gopxl/beep"
+	"github.com/gopxl/beep/effects"
+	"github.com/gopxl/beep/speaker"
+)
+
+const (
+	// Volume is exponential in base 2, so each step is roughly 3dB.
+	volumeBase = 2
+	minVolume  = -6
+	maxVolume  = 2
+)
+
+// Player sits between a queue of tracks and the speaker.
+//
+// Audio flows queue -> pause control -> FFT analysis -> volume -> speaker, so
+// the visualizer sees silence while paused and is unaffected by the volume.
+type Player struct {
+	format beep.Format
+	source *Queue
+
+	ctrl   *beep.Ctrl
+	fft    fft.FFTStreamerImpl
+	volume *effects.Volume
+}
+
+func NewPlayer(
+	ctx context.Context,
+	source *Queue,
+	hopSize uint32,
+	opts ...fft.FFTStreamerOption,
+) *Player {
+	format := source.Format()
+	p := &Player{
+		format: format,
+		source: source,
+		ctrl:   &beep.Ctrl{Streamer: source},
+	}
+	p.fft = fft.NewFFTStreamer(ctx, p.ctrl, hopSize, format, opts...)
+	p.volume = &effects.Volume{Streamer: &p.fft, Base: volumeBase}
+
+	return p
+}
+
+// Streamer is what should be handed to speaker.Play.
+func (p *Player) Streamer() beep.Streamer {
+	return p.volume
+}
+
+// FFTStreamer is what should be handed to the UI update loop.
+func (p *Player) FFTStreamer() *fft.FFTStreamerImpl {
+	return &p.fft
+}
+
+func (p *Player) TogglePause() {
+	speaker.Lock()
+	defer speaker.Unlock()
+
+	p.ctrl.Paused = !p.ctrl.Paused
+}
+
+func (p *Player) Paused() bool {
+	speaker.Lock()
+	defer speaker.Unlock()
+
+	return p.ctrl.Paused
+}
+
+// Seek moves playback by offset (negative to rewind), clamped to the track.
+func (p *Player) Seek(offset time.Duration) error {
+	speaker.Lock()
+	defer speaker.Unlock()
+
+	return p.seek(p.source.Position() + p.format.SampleRate.N(offset))
+}
+
+// SeekTo moves playback to position from the start, clamped to the track.
+func (p *Player) SeekTo(position time.Duration) error {
+	speaker.Lock()
+	defer speaker.Unlock()
+
+	return p.seek(p.format.SampleRate.N(position))
+}
+
+// Expects the speaker to be locked.
+func (p *Player) seek(pos int) error {
+	pos = min(max(pos, 0), p.source.Len())
+	if err := p.source.Seek(pos); err != nil {
+		return err
+	}
+
+	p.fft.Flush()
+
+	return nil
+}
+
+// NextTrack skips to the next track in the queue.
+func (p *Player) NextTrack() {
+	speaker.Lock()
+	defer speaker.Unlock()
+
+	p.source.Next()
+	p.fft.Flush()
+}
+
+// PreviousTrack goes back to the previous track in the queue.
+func (p *Player) PreviousTrack() {
+	speaker.Lock()
+	defer speaker.Unlock()
+
+	p.source.Previous()
+	p.fft.Flush()
+}
+
+// Title names the track currently playing.
+func (p *Player) Title() string {
+	speaker.Lock()
+	defer speaker.Unlock()
+
+	return p.source.Title()
+}
+
+// Seekable is false for streams like stdin, whose length is also unknown.
+func (p *Player) Seekable() bool {
+	speaker.Lock()
+	defer speaker.Unlock()
+
+	return p.source.Len() > 0
+}
+
+// Position is how far into the current track playback is.
+func (p *Player) Position() time.Duration {
+	speaker.Lock()
+	defer speaker.Unlock()
+
+	return p.format.SampleRate.D(p.source.Position())
+}
+
+// Duration is the total length of the current track, 0 if unknown.
+func (p *Player) Duration() time.Duration {
+	speaker.Lock()
+	defer speaker.Unlock()
+
+	return p.format.SampleRate.D(p.source.Len())
+}
+
+// AdjustVolume changes the volume by delta steps, muting at the bottom of the
+// range.
+func (p *Player) AdjustVolume(delta float64) {
+	speaker.Lock()
+	defer speaker.Unlock()
+
+	p.volume.Volume = min(max(p.volume.Volume+delta, minVolume), maxVolume)
+	p.volume.Silent = p.volume.Volume <= minVolume
+}
@@ -0,0 +1,128 @@
+package audio
+
+import (
+	"bufio"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ExpandPaths turns a list of files, directories and .m3u/.pls playlists into
+// the audio files they refer to, in order. Directories are walked recursively
+// and only files with a registered extension are kept. [Stdin] and URLs are
+// passed through.
+func ExpandPaths(paths []string) ([]string, error) {
+	var files []string
+	for _, path := range paths {
+		if path == Stdin || isURL(path) {
+			files = append(files, path)
+			continue
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+
+		var expanded []string
+		switch {
+		case info.IsDir():
+			expanded, err = walkDir(path)
+		case isPlaylist(path):
+			expanded, err = readPlaylist(path)
+		default:
+			expanded = []string{path}
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		files = append(files, expanded...)
+	}
+
+	return files, nil
+}
+
+// FileTracks makes a [FileTrack] for each path.
+func FileTracks(paths []string) []Track {
+	tracks := make([]Track, len(paths))
+	for i, p := range paths {
+		tracks[i] = FileTrack(p)
+	}
+
+	return tracks
+}
+
+func walkDir(dir string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && supportedExtension(filepath.Ext(path)) {
+			files = append(files, path)
+		}
+
+		return nil
+	})
+
+	return files, err
+}
+
+func isPlaylist(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".m3u", ".m3u8", ".pls":
+		return true
+	}
+
+	return false
+}
+
+// Reads the entries of an .m3u or .pls playlist. Relative entries are
+// resolved against the playlist's directory.
+func readPlaylist(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	pls := strings.ToLower(filepath.Ext(path)) == ".pls"
+
+	var entries []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		var entry string
+		if pls {
+			// FileN=entry, everything else is metadata.
+			key, value, ok := strings.Cut(line, "=")
+			if !ok || !strings.HasPrefix(strings.ToLower(key), "file") {
+				continue
+			}
+			entry = strings.TrimSpace(value)
+		} else {
+			if strings.HasPrefix(line, "#") {
+				continue
+			}
+			entry = line
+		}
+
+		if entry == "" {
+			continue
+		}
+		if !filepath.IsAbs(entry) && !strings.Contains(entry, "://") {
+			entry = filepath.Join(filepath.Dir(path), entry)
+		}
+		entries = append(entries, entry)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading playlist %s: %w", path, err)
+	}
+
+	return entries, nil
+}
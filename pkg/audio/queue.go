@@ -0,0 +1,453 @@
+package audio
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/gopxl/beep"
+	"github.com/gopxl/beep/effects"
+)
+
+// Quality passed to beep.Resample for tracks that don't match the output
+// sample rate.
+const resampleQuality = 4
+
+// Track is one entry of a [Queue], opened lazily when it is reached.
+type Track struct {
+	Name string
+	Open func() (beep.StreamSeekCloser, beep.Format, error)
+	// Live tracks read a source that can't be read twice at once, like
+	// [Stdin], a FIFO or a radio stream, so they are only opened once the
+	// track before them has ended instead of while it plays.
+	Live bool
+}
+
+// Stdin is the path that stands for standard input.
+const Stdin = "-"
+
+// FileTrack is a track decoded from the file at path with [Decode]. The path
+// may also be a FIFO, [Stdin] or an http(s) URL (see [HTTPTrack]).
+func FileTrack(path string) Track {
+	if path == Stdin {
+		return StdinTrack()
+	}
+	if isURL(path) {
+		return HTTPTrack(path)
+	}
+
+	return Track{
+		Name: filepath.Base(path),
+		Live: isPipe(path),
+		Open: func() (beep.StreamSeekCloser, beep.Format, error) {
+			f, err := os.Open(path)
+			if err != nil {
+				return nil, beep.Format{}, fmt.Errorf("error opening file: %w", err)
+			}
+
+			s, format, err := Decode(f, path)
+			if err != nil {
+				f.Close()
+				return nil, beep.Format{}, err
+			}
+
+			return s, format, nil
+		},
+	}
+}
+
+// StdinTrack is a track decoded from standard input with [Decode].
+func StdinTrack() Track {
+	return Track{
+		Name: "stdin",
+		Live: true,
+		Open: func() (beep.StreamSeekCloser, beep.Format, error) {
+			return Decode(os.Stdin, Stdin)
+		},
+	}
+}
+
+// Whether path is a FIFO or a device like /dev/stdin, which can only be read
+// through once.
+func isPipe(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Mode()&(os.ModeNamedPipe|os.ModeCharDevice) != 0
+}
+
+// Queue plays tracks back to back as a single stream in the output format of
+// the first track, resampling any that differ. The next track is opened in the
+// background while the current one plays so there is no gap between them, and
+// they can optionally be crossfaded. [Track.Live] tracks are the exception,
+// opened only once the track before them ends and never crossfaded into.
+//
+// Position, Len and Seek refer to the current track, in output samples. Len is
+// 0 when the length of the current track is unknown.
+type Queue struct {
+	tracks    []Track
+	order     []int
+	index     int
+	repeat    bool
+	shuffle   bool
+	crossfade time.Duration
+	format    beep.Format
+
+	current       beep.StreamSeekCloser
+	currentFormat beep.Format
+	// current, resampled to the output format if needed.
+	streamer beep.Streamer
+
+	next *preload
+
+	// Set while crossfading out of the previous track into current.
+	outgoing beep.StreamSeekCloser
+	mix      beep.Streamer
+	fadeLeft int
+	// Whether a crossfade out of current was attempted and could not start.
+	fadeFailed bool
+}
+
+// A track being opened in the background.
+type preload struct {
+	index  int
+	done   chan struct{}
+	s      beep.StreamSeekCloser
+	format beep.Format
+	err    error
+}
+
+type QueueOption func(*Queue)
+
+// WithShuffle plays the tracks in a random order.
+func WithShuffle(s bool) QueueOption {
+	return func(q *Queue) {
+		q.shuffle = s
+	}
+}
+
+// WithRepeat starts over from the first track instead of ending.
+func WithRepeat(r bool) QueueOption {
+	return func(q *Queue) {
+		q.repeat = r
+	}
+}
+
+// WithCrossfade blends the end of each track into the start of the next over
+// d. Zero plays them back to back.
+func WithCrossfade(d time.Duration) QueueOption {
+	return func(q *Queue) {
+		q.crossfade = d
+	}
+}
+
+// NewQueue opens the first playable track, whose format becomes the output
+// format of the whole queue.
+func NewQueue(tracks []Track, opts ...QueueOption) (*Queue, error) {
+	q := &Queue{tracks: tracks}
+	for _, opt := range opts {
+		opt(q)
+	}
+
+	q.order = make([]int, len(tracks))
+	for i := range q.order {
+		q.order[i] = i
+	}
+	if q.shuffle {
+		rand.Shuffle(len(q.order), func(i, j int) {
+			q.order[i], q.order[j] = q.order[j], q.order[i]
+		})
+	}
+
+	var errs []error
+	for q.index = 0; q.index < len(q.order); q.index++ {
+		s, format, err := q.tracks[q.order[q.index]].Open()
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		q.format = format
+		q.setCurrent(s, format)
+		return q, nil
+	}
+
+	return nil, fmt.Errorf("no playable tracks: %w", errors.Join(errs...))
+}
+
+// Format is the output format of the queue.
+func (q *Queue) Format() beep.Format {
+	return q.format
+}
+
+// Title names the track currently playing, preferring the title the stream
+// reports itself if it is a [Titler].
+func (q *Queue) Title() string {
+	if q.current == nil {
+		return ""
+	}
+
+	if t, ok := q.current.(Titler); ok && t.Title() != "" {
+		return t.Title()
+	}
+
+	return q.tracks[q.order[q.index]].Name
+}
+
+func (q *Queue) Stream(samples [][2]float64) (int, bool) {
+	n := 0
+	for n < len(samples) && q.current != nil {
+		if q.fadeLeft > 0 {
+			sn, _ := q.mix.Stream(samples[n:min(len(samples), n+q.fadeLeft)])
+			n += sn
+			q.fadeLeft -= sn
+			if q.fadeLeft == 0 || sn == 0 {
+				q.stopCrossfade()
+			}
+			continue
+		}
+
+		toStream := samples[n:]
+		untilFade := q.untilCrossfade()
+		if untilFade == 0 && q.startCrossfade() {
+			continue
+		}
+		if untilFade > 0 && untilFade < len(toStream) {
+			toStream = toStream[:untilFade]
+		}
+
+		sn, ok := q.streamer.Stream(toStream)
+		n += sn
+		if !ok {
+			q.advance(1)
+		}
+	}
+
+	return n, n > 0
+}
+
+func (q *Queue) Err() error {
+	if q.current == nil {
+		return nil
+	}
+
+	return q.current.Err()
+}
+
+func (q *Queue) Len() int {
+	if q.current == nil {
+		return 0
+	}
+
+	return q.toOutput(q.current.Len())
+}
+
+func (q *Queue) Position() int {
+	if q.current == nil {
+		return 0
+	}
+
+	return q.toOutput(q.current.Position())
+}
+
+// Seek cuts any crossfade short, as the outgoing track is no longer relevant.
+func (q *Queue) Seek(p int) error {
+	if q.current == nil {
+		return nil
+	}
+
+	q.stopCrossfade()
+	q.fadeFailed = false
+
+	return q.current.Seek(q.currentFormat.SampleRate.N(q.format.SampleRate.D(p)))
+}
+
+// Next skips to the following track, ending the queue after the last one
+// unless repeating.
+func (q *Queue) Next() {
+	q.advance(1)
+}
+
+// Previous goes back a track, or restarts the first one.
+func (q *Queue) Previous() {
+	if q.index == 0 && !q.repeat {
+		_ = q.Seek(0)
+		return
+	}
+
+	q.advance(-1)
+}
+
+func (q *Queue) Close() error {
+	q.stopCrossfade()
+	q.discardPreload()
+
+	return q.closeCurrent()
+}
+
+func (q *Queue) closeCurrent() error {
+	if q.current == nil {
+		return nil
+	}
+
+	err := q.current.Close()
+	q.current = nil
+	q.streamer = nil
+
+	return err
+}
+
+// Moves step tracks along, skipping over any that fail to open.
+func (q *Queue) advance(step int) {
+	q.stopCrossfade()
+	q.closeCurrent()
+
+	for range len(q.order) {
+		i, ok := q.nextIndex(step)
+		if !ok {
+			q.discardPreload()
+			return
+		}
+		q.index = i
+
+		s, format, err := q.open(i)
+		if err != nil {
+			continue
+		}
+
+		q.setCurrent(s, format)
+		return
+	}
+}
+
+// The position in order step tracks away, wrapping around when repeating.
+func (q *Queue) nextIndex(step int) (int, bool) {
+	i := q.index + step
+	if i >= 0 && i < len(q.order) {
+		return i, true
+	}
+	if !q.repeat {
+		return 0, false
+	}
+
+	return (i%len(q.order) + len(q.order)) % len(q.order), true
+}
+
+// Opens the track at position i in order, using the preloaded one if that is
+// the one asked for.
+func (q *Queue) open(i int) (beep.StreamSeekCloser, beep.Format, error) {
+	if q.next != nil && q.next.index == i {
+		p := q.next
+		q.next = nil
+		<-p.done
+		return p.s, p.format, p.err
+	}
+
+	return q.tracks[q.order[i]].Open()
+}
+
+func (q *Queue) setCurrent(s beep.StreamSeekCloser, format beep.Format) {
+	q.current = s
+	q.currentFormat = format
+	q.streamer = s
+	if format.SampleRate != q.format.SampleRate {
+		q.streamer = beep.Resample(resampleQuality, format.SampleRate, q.format.SampleRate, s)
+	}
+	q.fadeFailed = false
+
+	q.preloadNext()
+}
+
+// Starts opening the following track so it is ready the moment it is needed.
+func (q *Queue) preloadNext() {
+	q.discardPreload()
+
+	i, ok := q.nextIndex(1)
+	if !ok || q.tracks[q.order[i]].Live {
+		return
+	}
+
+	p := &preload{index: i, done: make(chan struct{})}
+	track := q.tracks[q.order[i]]
+	go func() {
+		defer close(p.done)
+		p.s, p.format, p.err = track.Open()
+	}()
+	q.next = p
+}
+
+func (q *Queue) discardPreload() {
+	if q.next == nil {
+		return
+	}
+
+	p := q.next
+	q.next = nil
+	go func() {
+		<-p.done
+		if p.err == nil {
+			p.s.Close()
+		}
+	}()
+}
+
+// Output samples until the crossfade out of the current track should start,
+// or -1 if there will not be one.
+func (q *Queue) untilCrossfade() int {
+	if q.crossfade == 0 || q.fadeFailed || q.current.Len() == 0 {
+		return -1
+	}
+	if i, ok := q.nextIndex(1); !ok || q.tracks[q.order[i]].Live {
+		return -1
+	}
+
+	return max(q.Len()-q.Position()-q.format.SampleRate.N(q.crossfade), 0)
+}
+
+// Makes the next track current while the rest of the previous one fades out
+// underneath it.
+func (q *Queue) startCrossfade() bool {
+	i, _ := q.nextIndex(1)
+	s, format, err := q.open(i)
+	if err != nil {
+		q.fadeFailed = true
+		return false
+	}
+
+	fadeLen := max(q.Len()-q.Position(), 1)
+	out := effects.Transition(q.streamer, fadeLen, 1, 0, equalPowerFadeOut)
+
+	q.outgoing = q.current
+	q.index = i
+	q.setCurrent(s, format)
+
+	in := effects.Transition(q.streamer, fadeLen, 0, 1, effects.TransitionEqualPower)
+	q.mix = beep.Mix(out, in)
+	q.fadeLeft = fadeLen
+
+	return true
+}
+
+// The mirror of effects.TransitionEqualPower for going from 1 to 0, making the
+// gain cos(pπ/2) so the squares of both gains always add up to 1. Reusing
+// TransitionEqualPower would give 1-sin(pπ/2), dipping mid fade.
+func equalPowerFadeOut(percent float64) float64 {
+	return 1 - math.Cos(percent*0.5*math.Pi)
+}
+
+func (q *Queue) stopCrossfade() {
+	if q.outgoing == nil {
+		return
+	}
+
+	q.outgoing.Close()
+	q.outgoing = nil
+	q.mix = nil
+	q.fadeLeft = 0
+}
+
+func (q *Queue) toOutput(n int) int {
+	return q.format.SampleRate.N(q.currentFormat.SampleRate.D(n))
+}
@@ -0,0 +1,171 @@
+package audio
+
+import (
+	"errors"
+	"math"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gopxl/beep"
+)
+
+var testFormat = beep.Format{SampleRate: 1000, NumChannels: 2, Precision: 2}
+
+// A track of n samples all equal to value.
+func constTrack(value [2]float64, n int) Track {
+	return Track{
+		Name: "const",
+		Open: func() (beep.StreamSeekCloser, beep.Format, error) {
+			return &constStream{value: value, n: n}, testFormat, nil
+		},
+	}
+}
+
+type constStream struct {
+	value  [2]float64
+	n, pos int
+}
+
+func (s *constStream) Stream(samples [][2]float64) (int, bool) {
+	n := min(len(samples), s.n-s.pos)
+	for i := range n {
+		samples[i] = s.value
+	}
+	s.pos += n
+
+	return n, n > 0
+}
+
+func (s *constStream) Err() error    { return nil }
+func (s *constStream) Len() int      { return s.n }
+func (s *constStream) Position() int { return s.pos }
+func (s *constStream) Close() error  { return nil }
+
+func (s *constStream) Seek(p int) error {
+	s.pos = p
+	return nil
+}
+
+func TestQueueGapless(t *testing.T) {
+	first, second := [2]float64{0.5, 0.5}, [2]float64{-0.25, 0.75}
+	q, err := NewQueue([]Track{constTrack(first, 500), constTrack(second, 300)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer q.Close()
+
+	got := streamAll(t, q)
+	if len(got) != 800 {
+		t.Fatalf("streamed %d samples, want 800", len(got))
+	}
+	for i, s := range got {
+		want := first
+		if i >= 500 {
+			want = second
+		}
+		if s != want {
+			t.Fatalf("sample %d = %v, want %v", i, s, want)
+		}
+	}
+}
+
+func TestQueueCrossfadeEqualPower(t *testing.T) {
+	// The outgoing track only in the left channel and the incoming only in
+	// the right, so each channel is the gain of one of them.
+	const fade = 100
+	q, err := NewQueue([]Track{constTrack([2]float64{1, 0}, 500), constTrack([2]float64{0, 1}, 500)},
+		WithCrossfade(fade*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer q.Close()
+
+	got := streamAll(t, q)
+	if len(got) != 900 {
+		t.Fatalf("streamed %d samples, want the 900 of both tracks overlapping by %d", len(got), fade)
+	}
+	for i, s := range got[400:500] {
+		if power := s[0]*s[0] + s[1]*s[1]; math.Abs(power-1) > 1e-9 {
+			t.Errorf("summed power %d samples into the crossfade = %g, want 1", i, power)
+		}
+	}
+	if got[399] != [2]float64{1, 0} || got[500] != [2]float64{0, 1} {
+		t.Errorf("crossfade from %v to %v, want from the first track alone to the second", got[399], got[500])
+	}
+}
+
+// A source like stdin that can only be read by one stream at a time.
+type liveSource struct {
+	reading atomic.Bool
+	// Set if it was ever opened while being read.
+	conflict atomic.Bool
+}
+
+func (src *liveSource) track(value [2]float64, n int) Track {
+	return Track{
+		Name: "live",
+		Live: true,
+		Open: func() (beep.StreamSeekCloser, beep.Format, error) {
+			if !src.reading.CompareAndSwap(false, true) {
+				src.conflict.Store(true)
+				return nil, beep.Format{}, errors.New("opened while still being read")
+			}
+			return &liveStream{constStream: constStream{value: value, n: n}, src: src}, testFormat, nil
+		},
+	}
+}
+
+type liveStream struct {
+	constStream
+	src *liveSource
+}
+
+func (s *liveStream) Close() error {
+	s.src.reading.Store(false)
+	return nil
+}
+
+func TestQueueOpensLiveTracksAfterTheLastEnds(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []QueueOption
+	}{
+		{"gapless", nil},
+		// No crossfade into a live track, it would have to be open while the
+		// track before it plays.
+		{"crossfade", []QueueOption{WithCrossfade(100 * time.Millisecond)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The same source listed twice and repeated, like - - with --repeat.
+			var src liveSource
+			value := [2]float64{0.5, -0.5}
+			q, err := NewQueue([]Track{src.track(value, 500), src.track(value, 500)},
+				append(tt.opts, WithRepeat(true))...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer q.Close()
+			// Anything preloaded is opened while the first track plays.
+			if q.next != nil {
+				<-q.next.done
+			}
+
+			// Through both tracks and into the first again.
+			buf := make([][2]float64, 1200)
+			if n, _ := q.Stream(buf); n != len(buf) {
+				t.Fatalf("streamed %d samples, want %d", n, len(buf))
+			}
+			for i, s := range buf {
+				if s != value {
+					t.Fatalf("sample %d = %v, want %v", i, s, value)
+				}
+			}
+			if src.conflict.Load() {
+				t.Error("opened a live track while the one before it was still being read")
+			}
+		})
+	}
+}
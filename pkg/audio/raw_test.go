@@ -0,0 +1,150 @@
+package audio
+
+import (
+	"bytes"
+	"io"
+	"slices"
+	"testing"
+)
+
+type seekableBuffer struct {
+	*bytes.Reader
+}
+
+func (seekableBuffer) Close() error { return nil }
+
+func TestParseSampleFormat(t *testing.T) {
+	for f, name := range sampleFormatNames {
+		got, err := ParseSampleFormat(name)
+		if err != nil || got != f {
+			t.Errorf("ParseSampleFormat(%q) = %v, %v, want %v", name, got, err, f)
+		}
+	}
+
+	if _, err := ParseSampleFormat("s20"); err == nil {
+		t.Error("parsed an unknown sample format")
+	}
+}
+
+func TestDecodeRaw(t *testing.T) {
+	tests := []struct {
+		name   string
+		format RawFormat
+		data   []byte
+		want   [][2]float64
+	}{
+		{
+			name:   "s16 little endian stereo",
+			format: RawFormat{NumChannels: 2, SampleFormat: S16},
+			data:   []byte{0x00, 0x40, 0x00, 0xc0, 0xff, 0x7f, 0x00, 0x80},
+			want:   [][2]float64{{0.5, -0.5}, {32767.0 / 32768, -1}},
+		},
+		{
+			name:   "s16 big endian stereo",
+			format: RawFormat{NumChannels: 2, SampleFormat: S16, BigEndian: true},
+			data:   []byte{0x40, 0x00, 0xc0, 0x00, 0x7f, 0xff, 0x80, 0x00},
+			want:   [][2]float64{{0.5, -0.5}, {32767.0 / 32768, -1}},
+		},
+		{
+			name:   "s16 mono on both channels",
+			format: RawFormat{NumChannels: 1, SampleFormat: S16},
+			data:   []byte{0x00, 0x20, 0x00, 0xe0},
+			want:   [][2]float64{{0.25, 0.25}, {-0.25, -0.25}},
+		},
+		{
+			name:   "s24 little endian stereo",
+			format: RawFormat{NumChannels: 2, SampleFormat: S24},
+			data:   []byte{0x00, 0x00, 0x40, 0x00, 0x00, 0xc0, 0x01, 0x00, 0x00, 0xff, 0xff, 0xff},
+			want:   [][2]float64{{0.5, -0.5}, {1.0 / (1 << 23), -1.0 / (1 << 23)}},
+		},
+		{
+			name:   "s24 big endian stereo",
+			format: RawFormat{NumChannels: 2, SampleFormat: S24, BigEndian: true},
+			data:   []byte{0x40, 0x00, 0x00, 0xc0, 0x00, 0x00, 0x00, 0x00, 0x01, 0xff, 0xff, 0xff},
+			want:   [][2]float64{{0.5, -0.5}, {1.0 / (1 << 23), -1.0 / (1 << 23)}},
+		},
+		{
+			name:   "f32 little endian stereo",
+			format: RawFormat{NumChannels: 2, SampleFormat: F32},
+			data:   []byte{0x00, 0x00, 0x00, 0x3f, 0x00, 0x00, 0x80, 0xbe},
+			want:   [][2]float64{{0.5, -0.25}},
+		},
+		{
+			name:   "f32 big endian stereo",
+			format: RawFormat{NumChannels: 2, SampleFormat: F32, BigEndian: true},
+			data:   []byte{0x3f, 0x00, 0x00, 0x00, 0xbe, 0x80, 0x00, 0x00},
+			want:   [][2]float64{{0.5, -0.25}},
+		},
+		{
+			name:   "only the first two of more channels",
+			format: RawFormat{NumChannels: 4, SampleFormat: S16},
+			data:   []byte{0x00, 0x40, 0x00, 0xc0, 0xff, 0x7f, 0xff, 0x7f},
+			want:   [][2]float64{{0.5, -0.5}},
+		},
+		{
+			name:   "truncated final frame dropped",
+			format: RawFormat{NumChannels: 2, SampleFormat: S24},
+			data:   []byte{0x00, 0x00, 0x40, 0x00, 0x00, 0xc0, 0x00, 0x00, 0x40, 0x00},
+			want:   [][2]float64{{0.5, -0.5}},
+		},
+		{
+			name:   "only a partial frame",
+			format: RawFormat{NumChannels: 1, SampleFormat: F32},
+			data:   []byte{0x00, 0x00, 0x00},
+			want:   nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.format.SampleRate = 44100
+
+			for name, r := range map[string]io.ReadCloser{
+				"stream":   io.NopCloser(bytes.NewReader(tt.data)),
+				"seekable": seekableBuffer{bytes.NewReader(tt.data)},
+			} {
+				s, format, err := DecodeRaw(r, tt.format)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if format.NumChannels != min(tt.format.NumChannels, 2) || format.Precision != tt.format.SampleFormat.Size() {
+					t.Errorf("%s: format = %+v", name, format)
+				}
+
+				if got := streamAll(t, s); !slices.Equal(got, tt.want) {
+					t.Errorf("%s: decoded %v, want %v", name, got, tt.want)
+				}
+				if err := s.Err(); err != nil {
+					t.Errorf("%s: %v", name, err)
+				}
+
+				wantLen := len(tt.want)
+				if name == "stream" {
+					wantLen = 0
+				}
+				if s.Len() != wantLen {
+					t.Errorf("%s: Len() = %d, want %d", name, s.Len(), wantLen)
+				}
+			}
+		})
+	}
+}
+
+func TestDecodeRawSeek(t *testing.T) {
+	data := []byte{0x00, 0x10, 0x00, 0x20, 0x00, 0x30}
+	s, _, err := DecodeRaw(seekableBuffer{bytes.NewReader(data)},
+		RawFormat{SampleRate: 44100, NumChannels: 1, SampleFormat: S16})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Seek(2); err != nil {
+		t.Fatal(err)
+	}
+	if got := streamAll(t, s); !slices.Equal(got, [][2]float64{{0.375, 0.375}}) {
+		t.Errorf("after seeking decoded %v, want the last frame", got)
+	}
+	if err := s.Seek(4); err == nil {
+		t.Error("seeked past the end")
+	}
+}
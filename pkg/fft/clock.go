@@ -0,0 +1,57 @@
+package fft
+
+import (
+	"sync"
+	"time"
+
+	"github.com/gopxl/beep"
+)
+
+// presentationClock estimates which output sample is being heard right now.
+//
+// The speaker pulls samples well before playing them, so right after a pull
+// the audible position is latency samples behind everything streamed so far,
+// and it advances in real time from there until the next pull.
+type presentationClock struct {
+	mu sync.Mutex
+
+	rate    beep.SampleRate
+	latency int
+
+	streamed int
+	pulledAt time.Time
+}
+
+// Records that n more samples were handed to the speaker.
+func (c *presentationClock) pulled(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.streamed += n
+	c.pulledAt = time.Now()
+}
+
+// Total samples handed to the speaker, the offset of the next one.
+func (c *presentationClock) streamedSamples() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.streamed
+}
+
+// Offset of the sample being heard now, never past what was streamed.
+func (c *presentationClock) position() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.pulledAt.IsZero() {
+		return -c.latency
+	}
+
+	return min(c.streamed-c.latency+c.rate.N(time.Since(c.pulledAt)), c.streamed)
+}
+
+// How long until the sample at offset is heard, negative if it already was.
+func (c *presentationClock) until(offset int) time.Duration {
+	return c.rate.D(offset - c.position())
+}
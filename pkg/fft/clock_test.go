@@ -0,0 +1,73 @@
+package fft
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gopxl/beep"
+)
+
+const testRate = beep.SampleRate(1000)
+
+func TestClockBeforeFirstPull(t *testing.T) {
+	c := &presentationClock{rate: testRate, latency: 200}
+
+	if p := c.position(); p != -200 {
+		t.Errorf("position = %d, want -200 until the speaker pulls anything", p)
+	}
+}
+
+func TestClockPosition(t *testing.T) {
+	tests := []struct {
+		name      string
+		sincePull time.Duration
+		want      int
+	}{
+		// Right after a pull everything streamed is still in the speaker's
+		// buffer.
+		{"just pulled", 0, 800},
+		{"playing", 150 * time.Millisecond, 950},
+		// The speaker never plays what it has not pulled yet.
+		{"starved", time.Second, 1000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &presentationClock{rate: testRate, latency: 200}
+			c.pulled(600)
+			c.pulled(400)
+			c.pulledAt = c.pulledAt.Add(-tt.sincePull)
+
+			// Allow for the time the test itself takes.
+			if p := c.position(); p < tt.want || p > tt.want+20 {
+				t.Errorf("position = %d, want %d", p, tt.want)
+			}
+			if n := c.streamedSamples(); n != 1000 {
+				t.Errorf("streamed %d samples, want 1000", n)
+			}
+		})
+	}
+}
+
+func TestClockUntil(t *testing.T) {
+	c := &presentationClock{rate: testRate, latency: 200}
+	c.pulled(1000)
+
+	if d := c.until(900); d < 80*time.Millisecond || d > 100*time.Millisecond {
+		t.Errorf("until 100 samples ahead = %v, want 100ms", d)
+	}
+	if d := c.until(700); d > -80*time.Millisecond {
+		t.Errorf("until 100 samples behind = %v, want -100ms", d)
+	}
+}
+
+func TestWithOutputLatency(t *testing.T) {
+	f := NewFFTStreamer(context.Background(), &rampStreamer{}, 100,
+		beep.Format{SampleRate: testRate, NumChannels: 2, Precision: 2},
+		WithOutputLatency(250*time.Millisecond))
+
+	if f.clock.latency != 250 {
+		t.Errorf("latency = %d samples, want 250", f.clock.latency)
+	}
+}
@@ -3,7 +3,10 @@ package fft
 import (
 	"context"
 	"errors"
+	"sync/atomic"
+	"time"
 
+	"github.com/brandonpollack23/goldsmith/pkg/loudness"
 	"github.com/gopxl/beep"
 	"github.com/mjibson/go-dsp/fft"
 	"github.com/mjibson/go-dsp/window"
@@ -28,66 +31,176 @@ type FFTStreamerImpl struct {
 	ctx context.Context
 	s   beep.Streamer
 
-	fftWindowSize        uint32
+	analysis             analysisConfig
 	fftWindowBuffer      [][2]float64
 	fftWindowBufferStart uint32
 
 	doFFTDone     <-chan error
-	fftInputChan  chan [][2]float64
+	fftInputChan  chan fftChunk
 	fftWindowChan <-chan FFTWindow
 
-	// Synchronization signal to update FFT to display.
-	fftUpdateSignalChan  chan struct{}
-	bytesSinceLastWindow uint32
+	// Decides when each window is audible, and so when to display it.
+	clock *presentationClock
+
+	// Bumped on every [FFTStreamerImpl.Flush] so windows computed from audio
+	// that was thrown away are never displayed.
+	generation *atomic.Uint64
+}
+
+// How the stream is cut into windows: every hopSize samples the latest
+// fftSize samples are windowed and transformed, so windows overlap whenever
+// fftSize > hopSize.
+type analysisConfig struct {
+	fftSize    uint32
+	hopSize    uint32
+	windowFunc WindowFunc
+	// Also transform each channel and their difference.
+	channelSpectra bool
+	sampleRate     beep.SampleRate
+}
+
+// A chunk of audio handed to the FFT goroutine, tagged with the generation it
+// was read in and the output offset of its first sample.
+type fftChunk struct {
+	samples    [][2]float64
+	offset     int
+	generation uint64
+}
+
+type FFTStreamerOption func(*FFTStreamerImpl)
+
+// WithFFTSize sets the number of samples transformed per window, which should
+// be a power of two. It defaults to the hop size.
+func WithFFTSize(n uint32) FFTStreamerOption {
+	return func(f *FFTStreamerImpl) {
+		f.analysis.fftSize = n
+	}
+}
+
+// WithWindowFunc sets the window applied before each FFT, Hann by default.
+func WithWindowFunc(w WindowFunc) FFTStreamerOption {
+	return func(f *FFTStreamerImpl) {
+		f.analysis.windowFunc = w
+	}
+}
+
+// WithChannelSpectra also fills in [FFTWindow.Left], [FFTWindow.Right] and
+// [FFTWindow.Side], which takes three more FFTs per window.
+func WithChannelSpectra(enabled bool) FFTStreamerOption {
+	return func(f *FFTStreamerImpl) {
+		f.analysis.channelSpectra = enabled
+	}
+}
+
+// WithOutputLatency is how long after being streamed a sample is actually
+// heard: the speaker's buffer plus any delay after it, like a Bluetooth
+// headset's. Windows are held back until their audio is heard.
+func WithOutputLatency(d time.Duration) FFTStreamerOption {
+	return func(f *FFTStreamerImpl) {
+		f.clock.latency = f.clock.rate.N(d)
+	}
 }
 
+// NewFFTStreamer starts a new FFT window every hopSize samples of streamer.
 func NewFFTStreamer(
 	ctx context.Context,
 	streamer beep.Streamer,
-	fftWindowSize uint32,
+	hopSize uint32,
 	format beep.Format,
+	opts ...FFTStreamerOption,
 ) FFTStreamerImpl {
-	internalBufferSize := fftWindowSize * bufferSizes
+	internalBufferSize := hopSize * bufferSizes
 
-	fftInputChan := make(chan [][2]float64, bufferSizes)
+	fftInputChan := make(chan fftChunk, bufferSizes)
 	fftOutputChan := make(chan FFTWindow, bufferSizes)
+	// Buffered so the FFT goroutine can finish, closing the window channel
+	// that ends NextFFTWindow, even if Err is never called.
+	doFFTDone := make(chan error, 1)
+
+	f := FFTStreamerImpl{
+		ctx: ctx,
+		s:   streamer,
+		analysis: analysisConfig{
+			fftSize:    hopSize,
+			hopSize:    hopSize,
+			windowFunc: window.Hann,
+			sampleRate: format.SampleRate,
+		},
+		fftWindowBuffer:      make([][2]float64, internalBufferSize),
+		fftWindowBufferStart: internalBufferSize,
+
+		doFFTDone:     doFFTDone,
+		fftInputChan:  fftInputChan,
+		fftWindowChan: fftOutputChan,
+		clock:         &presentationClock{rate: format.SampleRate},
+		generation:    &atomic.Uint64{},
+	}
+	for _, opt := range opts {
+		opt(&f)
+	}
 
-	doFFTDone := make(chan error)
 	go func() {
-		err := doFFTs(ctx, fftInputChan, fftOutputChan, fftWindowSize)
+		err := doFFTs(ctx, fftInputChan, fftOutputChan, f.analysis)
 		doFFTDone <- err
 		close(fftOutputChan)
 	}()
 
-	return FFTStreamerImpl{
-		ctx:                  ctx,
-		s:                    streamer,
-		fftWindowSize:        fftWindowSize,
-		fftWindowBuffer:      make([][2]float64, internalBufferSize),
-		fftWindowBufferStart: internalBufferSize,
-
-		doFFTDone:           doFFTDone,
-		fftInputChan:        fftInputChan,
-		fftWindowChan:       fftOutputChan,
-		fftUpdateSignalChan: make(chan struct{}, bufferSizes),
-	}
+	return f
 }
 
+// NextFFTWindow waits until the audio of the next window is being heard and
+// returns it. Windows that were missed by more than the hop size, or
+// computed from audio discarded by [FFTStreamerImpl.Flush], are skipped.
 func (f *FFTStreamerImpl) NextFFTWindow(ctx context.Context) (FFTWindow, bool, error) {
 	ctx, span := tracer.Start(ctx, "NextFFTWindow")
 	defer span.End()
 
-	select {
-	case _, ok := <-f.fftUpdateSignalChan:
-		if !ok {
-			return FFTWindow{}, false, nil
+	for {
+		var w FFTWindow
+		select {
+		case next, ok := <-f.fftWindowChan:
+			if !ok {
+				return FFTWindow{}, false, nil
+			}
+			w = next
+		case <-ctx.Done():
+			return FFTWindow{}, false, errors.New("fft streamer canceled")
+		}
+
+		if w.generation != f.generation.Load() {
+			continue
+		}
+
+		// Show the window when its middle is being heard.
+		wait := f.clock.until(w.Offset + len(w.Data)/2)
+		if wait < -f.clock.rate.D(int(f.analysis.hopSize)) {
+			// The next window is already due.
+			continue
+		}
+		if wait <= 0 {
+			return w, true, nil
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+			return w, true, nil
+		case <-ctx.Done():
+			timer.Stop()
+			return FFTWindow{}, false, errors.New("fft streamer canceled")
 		}
-		return <-f.fftWindowChan, true, nil
-	case <-ctx.Done():
-		return FFTWindow{}, false, errors.New("fft streamer canceled")
 	}
 }
 
+// Flush drops the audio already pulled from the underlying streamer along
+// with any FFT windows computed from it, so nothing from before a seek is
+// played or rendered. Call it with the speaker locked, right after seeking the
+// underlying streamer.
+func (f *FFTStreamerImpl) Flush() {
+	f.fftWindowBufferStart = uint32(len(f.fftWindowBuffer))
+	f.generation.Add(1)
+}
+
 func (f FFTStreamerImpl) Err() error {
 	sErr := f.s.Err()
 	if sErr != nil {
@@ -108,11 +221,11 @@ func (f *FFTStreamerImpl) Stream(samples [][2]float64) (int, bool) {
 
 	ctx, span = tracer.Start(ctx, "FFTStreamer.Stream.buffer")
 	copiedFromLastRead := copy(samples, f.fftWindowBuffer[f.fftWindowBufferStart:])
-	checkFFTSyncSignal(f, copiedFromLastRead)
 	span.End()
 
 	if copiedFromLastRead == len(samples) {
 		f.fftWindowBufferStart += uint32(copiedFromLastRead)
+		f.clock.pulled(copiedFromLastRead)
 		return copiedFromLastRead, true
 	}
 
@@ -122,33 +235,48 @@ func (f *FFTStreamerImpl) Stream(samples [][2]float64) (int, bool) {
 
 	copiedThisRead := copy(samples[copiedFromLastRead:], f.fftWindowBuffer)
 	f.fftWindowBufferStart = uint32(copiedThisRead)
-	checkFFTSyncSignal(f, copiedThisRead)
 
 	fftCopy := make([][2]float64, len(f.fftWindowBuffer))
 	copy(fftCopy, f.fftWindowBuffer)
-	f.fftInputChan <- fftCopy
+	f.fftInputChan <- fftChunk{
+		samples:    fftCopy,
+		offset:     f.clock.streamedSamples() + copiedFromLastRead,
+		generation: f.generation.Load(),
+	}
 
 	if !ok {
 		close(f.fftInputChan)
-		close(f.fftUpdateSignalChan)
 	}
 
-	return copiedFromLastRead + copiedThisRead, ok
-}
+	n := copiedFromLastRead + copiedThisRead
+	f.clock.pulled(n)
 
-func checkFFTSyncSignal(f *FFTStreamerImpl, bytesCopied int) {
-	f.bytesSinceLastWindow += uint32(bytesCopied)
-	if f.bytesSinceLastWindow >= f.fftWindowSize {
-		f.fftUpdateSignalChan <- struct{}{}
-		f.bytesSinceLastWindow -= f.fftWindowSize
-	}
+	return n, ok
 }
 
 type FFTWindow struct {
+	// Spectrum of the mid (mono) signal, the average of both channels.
 	Data []complex128
+	// Spectra of each channel and of the side signal, half their difference.
+	// Nil unless [WithChannelSpectra] is on.
+	Left  []complex128
+	Right []complex128
+	Side  []complex128
+	// The audio the spectra were computed from, before windowing.
+	Samples [][2]float64
+	// Levels measured up to the end of the window.
+	Levels loudness.Levels
+	// Offset of the window's first sample in the streamer's output.
+	Offset int
+	// What the two frequency bins of a full scale sine add up to in Data: the
+	// sum of the window function's coefficients. Dividing magnitudes by it
+	// makes them independent of FFT size and window function.
+	Gain float64
+
+	generation uint64
 }
 
-func doFFTs(ctx context.Context, fftInputChan chan [][2]float64, fftOutputChan chan FFTWindow, fftWindowSize uint32) error {
+func doFFTs(ctx context.Context, fftInputChan chan fftChunk, fftOutputChan chan FFTWindow, cfg analysisConfig) error {
 	ctx, span := tracer.Start(ctx, "FFT Manager")
 	defer span.End()
 
@@ -157,50 +285,96 @@ func doFFTs(ctx context.Context, fftInputChan chan [][2]float64, fftOutputChan c
 		return err
 	}
 
+	size, hop := int(cfg.fftSize), int(cfg.hopSize)
+	coefficients := cfg.windowFunc(size)
+	var gain float64
+	for _, c := range coefficients {
+		gain += c
+	}
+
+	// The last fftSize samples of the previous chunk, the start of windows
+	// overlapping into the next one.
+	history := make([][2]float64, size)
+	// Samples since the last window ended.
+	sinceHop := 0
+	var generation uint64
+
+	// Measures every sample once, in order, unlike the overlapping windows.
+	levelMeter := loudness.NewMeter(cfg.sampleRate)
+
 	for inChunk := range fftInputChan {
-		splits := splitSlices(inChunk, fftWindowSize)
+		if inChunk.generation != generation {
+			// Audio from before a flush has nothing to do with what follows.
+			clear(history)
+			sinceHop = 0
+			generation = inChunk.generation
+			levelMeter.Reset()
+		}
+
+		// buf[i] is the sample at offset inChunk.offset - size + i. It is never
+		// written to again, so windows can share it.
+		buf := append(history, inChunk.samples...)
+		firstEnd := size + hop - sinceHop
+
 		ctx, span := tracer.Start(
 			ctx,
 			"FFT Chunk",
 			trace.WithAttributes(attribute.KeyValue{
 				Key:   "numSlices",
-				Value: attribute.IntValue(len(splits)),
+				Value: attribute.IntValue(max(0, (len(buf)-firstEnd)/hop+1)),
 			}),
 		)
 
-		for _, in := range splits {
+		// How far into buf, past the history, has been measured.
+		measured := size
+		for end := firstEnd; end <= len(buf); end += hop {
 			ctx, span := tracer.Start(ctx, "fft")
 
-			timeDomain := toMono(in)
-			window.Apply(timeDomain, window.Hann)
-			freqDomain := fft.FFTReal(timeDomain)
+			levelMeter.Process(buf[measured:end])
+			measured = end
+
+			frame := buf[end-size : end]
+			w := FFTWindow{
+				Data:       transform(toMono(frame), coefficients),
+				Samples:    frame,
+				Levels:     levelMeter.Levels(),
+				Offset:     inChunk.offset - size + end - size,
+				Gain:       gain,
+				generation: inChunk.generation,
+			}
+			if cfg.channelSpectra {
+				w.Left = transform(channel(frame, 0), coefficients)
+				w.Right = transform(channel(frame, 1), coefficients)
+				w.Side = transform(toSide(frame), coefficients)
+			}
 
 			fftCount.Add(ctx, 1)
-			fftOutputChan <- FFTWindow{
-				Data: freqDomain,
-			}
+			fftOutputChan <- w
 
 			span.End()
 		}
 
+		levelMeter.Process(buf[measured:])
+		sinceHop = (sinceHop + len(inChunk.samples)) % hop
+		history = make([][2]float64, size)
+		copy(history, buf[len(buf)-size:])
+
 		span.End()
 	}
 
 	return nil
 }
 
-func splitSlices[T any](s []T, size uint32) [][]T {
-	var result [][]T
-	for i := 0; i < len(s); i += int(size) {
-		expectedEnd := i + int(size)
-		end := min(expectedEnd, len(s))
-
-		result = append(result, s[i:end])
+// Applies the window function and transforms to the frequency domain.
+func transform(timeDomain []float64, coefficients []float64) []complex128 {
+	for i, c := range coefficients {
+		timeDomain[i] *= c
 	}
 
-	return result
+	return fft.FFTReal(timeDomain)
 }
 
+// The mid signal, what both channels have in common.
 func toMono(x [][2]float64) []float64 {
 	result := make([]float64, len(x))
 	for i := range len(x) {
@@ -209,3 +383,22 @@ func toMono(x [][2]float64) []float64 {
 
 	return result
 }
+
+// The side signal, what differs between the channels.
+func toSide(x [][2]float64) []float64 {
+	result := make([]float64, len(x))
+	for i := range len(x) {
+		result[i] = (x[i][0] - x[i][1]) / 2
+	}
+
+	return result
+}
+
+func channel(x [][2]float64, c int) []float64 {
+	result := make([]float64, len(x))
+	for i := range len(x) {
+		result[i] = x[i][c]
+	}
+
+	return result
+}
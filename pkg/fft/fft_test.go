@@ -0,0 +1,265 @@
+package fft
+
+import (
+	"context"
+	"math"
+	"math/cmplx"
+	"testing"
+	"time"
+
+	"github.com/gopxl/beep"
+	"github.com/mjibson/go-dsp/fft"
+	"github.com/mjibson/go-dsp/window"
+)
+
+var testFormat = beep.Format{SampleRate: testRate, NumChannels: 2, Precision: 2}
+
+// Streams n samples, or forever if n is 0, of sample(offset).
+type rampStreamer struct {
+	n      int
+	pos    int
+	sample func(offset int) [2]float64
+}
+
+func (r *rampStreamer) Stream(samples [][2]float64) (int, bool) {
+	for i := range samples {
+		if r.sample != nil {
+			samples[i] = r.sample(r.pos)
+		}
+		r.pos++
+	}
+
+	return len(samples), r.n == 0 || r.pos < r.n
+}
+
+func (r *rampStreamer) Err() error {
+	return nil
+}
+
+// A different value in each channel at every offset.
+func ramp(offset int) [2]float64 {
+	return [2]float64{float64(offset + 1), -float64(offset+1) / 2}
+}
+
+// Pulls n samples through f the way the speaker would.
+func pull(f *FFTStreamerImpl, n int) {
+	buf := make([][2]float64, 128)
+	for n > 0 {
+		got, _ := f.Stream(buf[:min(n, len(buf))])
+		n -= got
+	}
+}
+
+// Every window computed from what was pulled, skipping the presentation
+// clock.
+func drain(f *FFTStreamerImpl) []FFTWindow {
+	var windows []FFTWindow
+	for w := range f.fftWindowChan {
+		windows = append(windows, w)
+	}
+
+	return windows
+}
+
+func assertSpectrum(t *testing.T, what string, got []complex128, timeDomain, coefficients []float64) {
+	t.Helper()
+
+	windowed := make([]float64, len(timeDomain))
+	for i, c := range coefficients {
+		windowed[i] = timeDomain[i] * c
+	}
+	want := fft.FFTReal(windowed)
+
+	if len(got) != len(want) {
+		t.Fatalf("%s has %d bins, want %d", what, len(got), len(want))
+	}
+	for k := range want {
+		if cmplx.Abs(got[k]-want[k]) > 1e-6*(1+cmplx.Abs(want[k])) {
+			t.Fatalf("%s bin %d = %v, want %v", what, k, got[k], want[k])
+		}
+	}
+}
+
+func TestWindowOffsets(t *testing.T) {
+	tests := []struct {
+		name          string
+		fftSize, hop  uint32
+		samples, want int
+	}{
+		{"no overlap", 100, 100, 2000, 20},
+		{"75% overlap", 400, 100, 2000, 20},
+		// Windows longer than a chunk of audio span several of them.
+		{"longer than a chunk", 2048, 100, 2000, 20},
+		{"gaps between windows", 50, 100, 2000, 20},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := NewFFTStreamer(context.Background(), &rampStreamer{n: tt.samples, sample: ramp}, tt.hop, testFormat,
+				WithFFTSize(tt.fftSize), WithWindowFunc(window.Rectangular))
+			pull(&f, tt.samples)
+			windows := drain(&f)
+
+			if len(windows) != tt.want {
+				t.Fatalf("got %d windows, want %d", len(windows), tt.want)
+			}
+			for i, w := range windows {
+				// Every hop a window ends, holding the latest fftSize samples.
+				wantOffset := (i+1)*int(tt.hop) - int(tt.fftSize)
+				if w.Offset != wantOffset {
+					t.Fatalf("window %d starts at %d, want %d", i, w.Offset, wantOffset)
+				}
+				if len(w.Samples) != int(tt.fftSize) {
+					t.Fatalf("window %d has %d samples, want %d", i, len(w.Samples), tt.fftSize)
+				}
+				for j, s := range w.Samples {
+					// Before the start of the stream is silence.
+					var want [2]float64
+					if offset := w.Offset + j; offset >= 0 {
+						want = ramp(offset)
+					}
+					if s != want {
+						t.Fatalf("window %d sample %d = %v, want %v", i, j, s, want)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestWindowFunctionApplied(t *testing.T) {
+	const size = 256
+	sine := func(offset int) [2]float64 {
+		x := math.Sin(2 * math.Pi * 10.3 * float64(offset) / size)
+		return [2]float64{x, x}
+	}
+
+	for _, name := range WindowFuncNames() {
+		t.Run(name, func(t *testing.T) {
+			windowFunc, _ := LookupWindowFunc(name)
+			f := NewFFTStreamer(context.Background(), &rampStreamer{n: 1280, sample: sine}, size, testFormat,
+				WithWindowFunc(windowFunc))
+			pull(&f, 1280)
+
+			coefficients := windowFunc(size)
+			var gain float64
+			for _, c := range coefficients {
+				gain += c
+			}
+
+			for _, w := range drain(&f) {
+				if w.Gain != gain {
+					t.Fatalf("gain = %g, want the sum of the coefficients, %g", w.Gain, gain)
+				}
+				assertSpectrum(t, "spectrum", w.Data, channel(w.Samples, 0), coefficients)
+			}
+		})
+	}
+}
+
+func TestChannelSpectra(t *testing.T) {
+	const size = 128
+	// Different tones in each channel.
+	stereo := func(offset int) [2]float64 {
+		return [2]float64{
+			math.Sin(2 * math.Pi * 5 * float64(offset) / size),
+			0.5 * math.Cos(2*math.Pi*17*float64(offset)/size),
+		}
+	}
+
+	f := NewFFTStreamer(context.Background(), &rampStreamer{n: 1280, sample: stereo}, size, testFormat,
+		WithChannelSpectra(true))
+	pull(&f, 1280)
+
+	coefficients := window.Hann(size)
+	windows := drain(&f)
+	if len(windows) == 0 {
+		t.Fatal("no windows")
+	}
+	for _, w := range windows {
+		left, right := channel(w.Samples, 0), channel(w.Samples, 1)
+		mid, side := make([]float64, size), make([]float64, size)
+		for i := range mid {
+			mid[i] = (left[i] + right[i]) / 2
+			side[i] = (left[i] - right[i]) / 2
+		}
+
+		assertSpectrum(t, "left", w.Left, left, coefficients)
+		assertSpectrum(t, "right", w.Right, right, coefficients)
+		assertSpectrum(t, "mid", w.Data, mid, coefficients)
+		assertSpectrum(t, "side", w.Side, side, coefficients)
+	}
+}
+
+func TestNoChannelSpectraByDefault(t *testing.T) {
+	f := NewFFTStreamer(context.Background(), &rampStreamer{n: 1000, sample: ramp}, 100, testFormat)
+	pull(&f, 1000)
+
+	for _, w := range drain(&f) {
+		if w.Left != nil || w.Right != nil || w.Side != nil {
+			t.Fatal("channel spectra computed without WithChannelSpectra")
+		}
+	}
+}
+
+// Windows are released once the middle of their audio is heard, and those
+// already behind by more than a hop are skipped.
+func TestNextFFTWindowFollowsClock(t *testing.T) {
+	f := NewFFTStreamer(context.Background(), &rampStreamer{sample: ramp}, 100, testFormat,
+		WithOutputLatency(500*time.Millisecond))
+	// Ten windows centred on 50 to 950 while 500 is being heard, so the first
+	// four are more than a hop behind.
+	pull(&f, 1000)
+
+	start := time.Now()
+	w, ok, err := f.NextFFTWindow(context.Background())
+	if !ok || err != nil {
+		t.Fatalf("NextFFTWindow() = %v, %v", ok, err)
+	}
+	if w.Offset != 400 {
+		t.Errorf("first window shown starts at %d, want 400, the latest due", w.Offset)
+	}
+	if waited := time.Since(start); waited > 30*time.Millisecond {
+		t.Errorf("waited %v for a window already due", waited)
+	}
+
+	// The next window's middle is 50ms of audio away.
+	w, ok, err = f.NextFFTWindow(context.Background())
+	if !ok || err != nil {
+		t.Fatalf("NextFFTWindow() = %v, %v", ok, err)
+	}
+	if w.Offset != 500 {
+		t.Errorf("second window starts at %d, want 500", w.Offset)
+	}
+	if waited := time.Since(start); waited < 40*time.Millisecond {
+		t.Errorf("showed a window %v after the last, before its audio is heard", waited)
+	}
+}
+
+// After a seek nothing computed from the audio before it is shown, and the
+// windows after it don't overlap into that audio.
+func TestFlushDropsStaleWindows(t *testing.T) {
+	f := NewFFTStreamer(context.Background(), &rampStreamer{sample: ramp}, 100, testFormat,
+		WithFFTSize(200), WithOutputLatency(1050*time.Millisecond))
+	pull(&f, 1000)
+	f.Flush()
+	pull(&f, 1000)
+
+	// 950 is being heard, so the last window before the flush, centred on
+	// 900, would be due. The first after it, centred on 1000, comes next.
+	w, ok, err := f.NextFFTWindow(context.Background())
+	if !ok || err != nil {
+		t.Fatalf("NextFFTWindow() = %v, %v", ok, err)
+	}
+	if w.generation != f.generation.Load() {
+		t.Fatalf("showed window at %d from before the flush", w.Offset)
+	}
+	if w.Offset != 900 {
+		t.Errorf("first window after the flush starts at %d, want 900", w.Offset)
+	}
+	for i, s := range w.Samples[:100] {
+		if s != [2]float64{} {
+			t.Fatalf("sample %d = %v from before the flush, want silence", i, s)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package fft
+
+import (
+	"math"
+	"slices"
+
+	"github.com/mjibson/go-dsp/window"
+)
+
+// WindowFunc returns the L coefficients of a window function, like the ones
+// in go-dsp/window.
+type WindowFunc func(L int) []float64
+
+var windowFuncs = map[string]WindowFunc{
+	"hann":            window.Hann,
+	"hamming":         window.Hamming,
+	"blackman_harris": blackmanHarris,
+	"flat_top":        window.FlatTop,
+	"rectangular":     window.Rectangular,
+}
+
+// WindowFuncNames lists the names accepted by [LookupWindowFunc], sorted.
+func WindowFuncNames() []string {
+	names := make([]string, 0, len(windowFuncs))
+	for name := range windowFuncs {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	return names
+}
+
+func LookupWindowFunc(name string) (WindowFunc, bool) {
+	w, ok := windowFuncs[name]
+	return w, ok
+}
+
+// 4 term Blackman-Harris, which go-dsp does not have.
+func blackmanHarris(L int) []float64 {
+	const (
+		a0 = 0.35875
+		a1 = 0.48829
+		a2 = 0.14128
+		a3 = 0.01168
+	)
+
+	r := make([]float64, L)
+	if L == 1 {
+		r[0] = 1
+		return r
+	}
+
+	N := float64(L - 1)
+	for n := range L {
+		x := 2 * math.Pi * float64(n) / N
+		r[n] = a0 - a1*math.Cos(x) + a2*math.Cos(2*x) - a3*math.Cos(3*x)
+	}
+
+	return r
+}
@@ -0,0 +1,48 @@
+package fft
+
+import (
+	"math"
+	"testing"
+)
+
+func TestWindowFuncs(t *testing.T) {
+	for _, name := range WindowFuncNames() {
+		t.Run(name, func(t *testing.T) {
+			w, ok := LookupWindowFunc(name)
+			if !ok {
+				t.Fatalf("%s is listed but not found", name)
+			}
+
+			// Symmetric, peaking at 1 in the middle.
+			const L = 65
+			coefficients := w(L)
+			if len(coefficients) != L {
+				t.Fatalf("got %d coefficients, want %d", len(coefficients), L)
+			}
+			for i := range L / 2 {
+				if math.Abs(coefficients[i]-coefficients[L-1-i]) > 1e-12 {
+					t.Errorf("coefficient %d = %g but %d = %g", i, coefficients[i], L-1-i, coefficients[L-1-i])
+				}
+			}
+			if c := coefficients[L/2]; math.Abs(c-1) > 1e-6 {
+				t.Errorf("middle coefficient = %g, want 1", c)
+			}
+
+			if one := w(1); len(one) != 1 || one[0] != 1 {
+				t.Errorf("window of one = %v, want [1]", one)
+			}
+		})
+	}
+
+	if _, ok := LookupWindowFunc("triangle"); ok {
+		t.Error("found an unknown window function")
+	}
+}
+
+func TestBlackmanHarrisEnds(t *testing.T) {
+	// The 4 term window's ends are a0 - a1 + a2 - a3.
+	coefficients := blackmanHarris(64)
+	if c := coefficients[0]; math.Abs(c-6e-5) > 1e-9 {
+		t.Errorf("first coefficient = %g, want 6e-5", c)
+	}
+}
@@ -0,0 +1,122 @@
+package loudness
+
+import "math"
+
+// biquad is a second order IIR filter in direct form I.
+type biquad struct {
+	b0, b1, b2, a1, a2 float64
+	x1, x2, y1, y2     float64
+}
+
+func (f *biquad) process(x float64) float64 {
+	y := f.b0*x + f.b1*f.x1 + f.b2*f.x2 - f.a1*f.y1 - f.a2*f.y2
+	f.x2, f.x1 = f.x1, x
+	f.y2, f.y1 = f.y1, y
+
+	return y
+}
+
+func (f *biquad) reset() {
+	f.x1, f.x2, f.y1, f.y2 = 0, 0, 0, 0
+}
+
+// The ITU-R BS.1770 K-weighting filter, a high shelf modelling the head
+// followed by a high pass, designed for any sample rate. At 48kHz these give
+// the coefficients in the standard.
+func kWeightingFilter(sampleRate float64) [2]biquad {
+	var shelf biquad
+	{
+		const (
+			f0 = 1681.974450955533
+			g  = 3.999843853973347
+			q  = 0.7071752369554196
+		)
+		k := math.Tan(math.Pi * f0 / sampleRate)
+		vh := math.Pow(10, g/20)
+		vb := math.Pow(vh, 0.4996667741545416)
+		a0 := 1 + k/q + k*k
+
+		shelf = biquad{
+			b0: (vh + vb*k/q + k*k) / a0,
+			b1: 2 * (k*k - vh) / a0,
+			b2: (vh - vb*k/q + k*k) / a0,
+			a1: 2 * (k*k - 1) / a0,
+			a2: (1 - k/q + k*k) / a0,
+		}
+	}
+
+	var highPass biquad
+	{
+		const (
+			f0 = 38.13547087602444
+			q  = 0.5003270373238773
+		)
+		k := math.Tan(math.Pi * f0 / sampleRate)
+		a0 := 1 + k/q + k*k
+
+		highPass = biquad{
+			b0: 1,
+			b1: -2,
+			b2: 1,
+			a1: 2 * (k*k - 1) / a0,
+			a2: (1 - k/q + k*k) / a0,
+		}
+	}
+
+	return [2]biquad{shelf, highPass}
+}
+
+const (
+	oversampling = 4
+	// Input samples each interpolated sample is computed from.
+	oversamplerTaps = 12
+)
+
+// Windowed sinc interpolation filter for each phase of the oversampler.
+var oversamplerPhases = func() [oversampling][oversamplerTaps]float64 {
+	var phases [oversampling][oversamplerTaps]float64
+	const half = oversamplerTaps / 2
+
+	for p := range phases {
+		for k := range oversamplerTaps {
+			// Distance from input sample k to the interpolated point.
+			u := float64(k-half) + float64(p)/oversampling
+			phases[p][k] = sinc(u) * 0.5 * (1 + math.Cos(math.Pi*u/half))
+		}
+	}
+
+	return phases
+}()
+
+func sinc(x float64) float64 {
+	if x == 0 {
+		return 1
+	}
+
+	return math.Sin(math.Pi*x) / (math.Pi * x)
+}
+
+// oversampler finds the peaks of a signal oversampled 4 times, for true peak
+// measurement as in ITU-R BS.1770.
+type oversampler struct {
+	// The most recent samples, newest first.
+	history [oversamplerTaps]float64
+}
+
+// peak takes the next sample and returns the largest magnitude among the
+// interpolated samples it completes.
+func (o *oversampler) peak(x float64) float64 {
+	copy(o.history[1:], o.history[:oversamplerTaps-1])
+	o.history[0] = x
+
+	var peak float64
+	for _, phase := range oversamplerPhases {
+		var y float64
+		for k, c := range phase {
+			y += c * o.history[k]
+		}
+		peak = max(peak, math.Abs(y))
+	}
+
+	return peak
+}
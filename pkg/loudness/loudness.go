@@ -0,0 +1,250 @@
+// Package loudness measures the levels of stereo audio: RMS, sample peak,
+// true peak and EBU R128 loudness.
+package loudness
+
+import (
+	"math"
+	"time"
+
+	"github.com/gopxl/beep"
+)
+
+// Measurements are in 100ms blocks, the step EBU R128 loudness is updated in.
+const blockDuration = 100 * time.Millisecond
+
+const (
+	// The integration time of a VU meter.
+	rmsBlocks       = 3
+	momentaryBlocks = 4
+	shortTermBlocks = 30
+
+	// EBU R128 integrated loudness gates.
+	absoluteGate = -70 // LUFS
+	relativeGate = -10 // LU
+
+	// Integrated loudness is gated to within a histogram bin, 0.1 LU wide from
+	// the absolute gate up to +30 LUFS, as in libebur128.
+	histogramStep = 0.1
+	histogramMax  = 30 // LUFS
+	histogramBins = (histogramMax - absoluteGate) / histogramStep
+)
+
+// Levels are a snapshot of a [Meter]. Everything is in dB, -Inf for silence.
+type Levels struct {
+	// Per channel RMS over the last 300ms, in dBFS.
+	RMS [2]float64
+	// Per channel sample peak over the last 100ms, in dBFS.
+	Peak [2]float64
+	// Per channel peak over the last 100ms of the audio oversampled 4 times,
+	// catching peaks between samples, in dBTP.
+	TruePeak [2]float64
+
+	// EBU R128 loudness over the last 400ms, the last 3s and everything so
+	// far, in LUFS.
+	Momentary  float64
+	ShortTerm  float64
+	Integrated float64
+}
+
+// Silence returns levels for no audio at all.
+func Silence() Levels {
+	inf := math.Inf(-1)
+	return Levels{
+		RMS:        [2]float64{inf, inf},
+		Peak:       [2]float64{inf, inf},
+		TruePeak:   [2]float64{inf, inf},
+		Momentary:  inf,
+		ShortTerm:  inf,
+		Integrated: inf,
+	}
+}
+
+// Meter measures audio fed to it through [Meter.Process] in order. It is not
+// safe for concurrent use.
+type Meter struct {
+	blockSize int
+
+	// K-weighting filter of each channel, for loudness.
+	kWeighting [2][2]biquad
+	truePeak   [2]oversampler
+
+	// The block being filled.
+	current   block
+	filled    int
+	completed []block
+
+	// Every 400ms window so far by loudness, for integrated loudness. Unlike
+	// keeping the windows themselves this stays the same size however long
+	// the audio runs.
+	histogram loudnessHistogram
+
+	levels Levels
+}
+
+type block struct {
+	// Sums of squares of the samples and of the K-weighted samples.
+	squares  [2]float64
+	weighted [2]float64
+	peak     [2]float64
+	truePeak [2]float64
+}
+
+func NewMeter(sampleRate beep.SampleRate) *Meter {
+	m := &Meter{
+		blockSize: max(1, sampleRate.N(blockDuration)),
+		levels:    Silence(),
+	}
+	for c := range m.kWeighting {
+		m.kWeighting[c] = kWeightingFilter(float64(sampleRate))
+	}
+
+	return m
+}
+
+// Process measures the next samples of the audio.
+func (m *Meter) Process(samples [][2]float64) {
+	for _, s := range samples {
+		for c, x := range s {
+			m.current.squares[c] += x * x
+			m.current.peak[c] = max(m.current.peak[c], math.Abs(x))
+			m.current.truePeak[c] = max(m.current.truePeak[c], m.truePeak[c].peak(x))
+
+			w := m.kWeighting[c][1].process(m.kWeighting[c][0].process(x))
+			m.current.weighted[c] += w * w
+		}
+
+		m.filled++
+		if m.filled == m.blockSize {
+			m.finishBlock()
+		}
+	}
+}
+
+// Reset forgets the filter state, recent blocks and the levels measured from
+// them, for when the audio jumps, as on a seek. Integrated loudness is kept.
+func (m *Meter) Reset() {
+	for c := range m.kWeighting {
+		m.kWeighting[c][0].reset()
+		m.kWeighting[c][1].reset()
+		m.truePeak[c] = oversampler{}
+	}
+	m.current = block{}
+	m.filled = 0
+	m.completed = nil
+
+	integrated := m.levels.Integrated
+	m.levels = Silence()
+	m.levels.Integrated = integrated
+}
+
+// Levels returns the measurements as of the last full block processed.
+func (m *Meter) Levels() Levels {
+	return m.levels
+}
+
+func (m *Meter) finishBlock() {
+	m.completed = append(m.completed, m.current)
+	if len(m.completed) > shortTermBlocks {
+		m.completed = m.completed[1:]
+	}
+	m.current = block{}
+	m.filled = 0
+
+	latest := m.completed[len(m.completed)-1]
+	for c := range 2 {
+		var squares float64
+		recent := m.completed[max(0, len(m.completed)-rmsBlocks):]
+		for _, b := range recent {
+			squares += b.squares[c]
+		}
+		m.levels.RMS[c] = powerToDB(squares / float64(len(recent)*m.blockSize))
+		m.levels.Peak[c] = amplitudeToDB(latest.peak[c])
+		m.levels.TruePeak[c] = amplitudeToDB(latest.truePeak[c])
+	}
+
+	if len(m.completed) >= momentaryBlocks {
+		power := m.weightedPower(momentaryBlocks)
+		m.histogram.add(power)
+		m.levels.Momentary = powerToLUFS(power)
+		m.levels.Integrated = m.histogram.integrated()
+	}
+	if len(m.completed) >= shortTermBlocks {
+		m.levels.ShortTerm = powerToLUFS(m.weightedPower(shortTermBlocks))
+	}
+}
+
+// Mean square K-weighted power of the last n blocks, summed over channels.
+func (m *Meter) weightedPower(n int) float64 {
+	var power float64
+	for _, b := range m.completed[len(m.completed)-n:] {
+		power += b.weighted[0] + b.weighted[1]
+	}
+
+	return power / float64(n*m.blockSize)
+}
+
+// The number and summed mean square power of 400ms windows in each
+// histogram bin. Windows below the absolute gate are left out.
+type loudnessHistogram struct {
+	counts [histogramBins]int
+	powers [histogramBins]float64
+}
+
+func (h *loudnessHistogram) add(power float64) {
+	lufs := powerToLUFS(power)
+	if !(lufs > absoluteGate) {
+		return
+	}
+
+	bin := histogramBin(lufs)
+	h.counts[bin]++
+	h.powers[bin] += power
+}
+
+// Gated loudness of 400ms windows overlapping by 75%, per EBU R128: windows
+// below the absolute gate are dropped, then those more than 10 LU below the
+// loudness of what is left.
+func (h *loudnessHistogram) integrated() float64 {
+	first := 0
+	if relative := powerToLUFS(h.meanPower(0)) + relativeGate; relative > absoluteGate {
+		first = histogramBin(relative)
+	}
+
+	return powerToLUFS(h.meanPower(first))
+}
+
+// Mean power of the windows in bins first and up, 0 if there are none.
+func (h *loudnessHistogram) meanPower(first int) float64 {
+	var sum float64
+	var n int
+	for i := first; i < histogramBins; i++ {
+		sum += h.powers[i]
+		n += h.counts[i]
+	}
+	if n == 0 {
+		return 0
+	}
+
+	return sum / float64(n)
+}
+
+// Anything louder than the histogram goes in its top bin.
+func histogramBin(lufs float64) int {
+	if lufs >= histogramMax {
+		return histogramBins - 1
+	}
+
+	return int((lufs - absoluteGate) / histogramStep)
+}
+
+func powerToLUFS(p float64) float64 {
+	return -0.691 + 10*math.Log10(p)
+}
+
+func powerToDB(p float64) float64 {
+	return 10 * math.Log10(p)
+}
+
+func amplitudeToDB(a float64) float64 {
+	return 20 * math.Log10(a)
+}
@@ -0,0 +1,161 @@
+package loudness
+
+import (
+	"math"
+	"testing"
+
+	"github.com/gopxl/beep"
+)
+
+const testRate = beep.SampleRate(48000)
+
+// A stereo sine of amplitude dBFS at freq Hz and phase radians, lasting
+// seconds.
+func sine(dbfs, freq, phase, seconds float64) [][2]float64 {
+	amplitude := math.Pow(10, dbfs/20)
+	samples := make([][2]float64, int(seconds*float64(testRate)))
+	for i := range samples {
+		x := amplitude * math.Sin(2*math.Pi*freq*float64(i)/float64(testRate)+phase)
+		samples[i] = [2]float64{x, x}
+	}
+
+	return samples
+}
+
+func assertNear(t *testing.T, what string, got, want, tolerance float64) {
+	t.Helper()
+
+	if math.Abs(got-want) > tolerance {
+		t.Errorf("%s = %.2f, want %.2f", what, got, want)
+	}
+}
+
+// EBU Tech 3341's reference: a 1 kHz stereo sine at -23 dBFS is -23 LUFS.
+func TestSineLoudness(t *testing.T) {
+	m := NewMeter(testRate)
+	m.Process(sine(-23, 1000, 0, 5))
+	l := m.Levels()
+
+	assertNear(t, "momentary", l.Momentary, -23, 0.1)
+	assertNear(t, "short term", l.ShortTerm, -23, 0.1)
+	assertNear(t, "integrated", l.Integrated, -23, 0.1)
+	for c := range 2 {
+		// The RMS of a sine is 3dB below its peak.
+		assertNear(t, "RMS", l.RMS[c], -23-3.01, 0.05)
+		assertNear(t, "peak", l.Peak[c], -23, 0.05)
+		assertNear(t, "true peak", l.TruePeak[c], -23, 0.1)
+	}
+}
+
+func TestSilence(t *testing.T) {
+	m := NewMeter(testRate)
+	m.Process(make([][2]float64, int(testRate)))
+	l := m.Levels()
+
+	for name, v := range map[string]float64{
+		"momentary": l.Momentary, "integrated": l.Integrated, "RMS": l.RMS[0], "peak": l.Peak[0],
+	} {
+		if !math.IsInf(v, -1) {
+			t.Errorf("%s of silence = %g, want -Inf", name, v)
+		}
+	}
+}
+
+func TestIntegratedGating(t *testing.T) {
+	tests := []struct {
+		name  string
+		quiet float64
+		want  float64
+	}{
+		// Below the absolute gate of -70 LUFS.
+		{"absolute", -80, -23},
+		// Above the absolute gate but more than 10 LU below the rest.
+		{"relative", -43, -23},
+		// Within 10 LU, so it counts: half the time at -23 and half at -28.
+		{"ungated", -28, 10*math.Log10((1+math.Pow(10, -0.5))/2) - 23},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMeter(testRate)
+			m.Process(sine(-23, 1000, 0, 10))
+			m.Process(sine(tt.quiet, 1000, 0, 10))
+
+			assertNear(t, "integrated", m.Levels().Integrated, tt.want, 0.1)
+		})
+	}
+}
+
+// The histogram gates to within a bin of keeping every window and gating
+// them exactly.
+func TestHistogramMatchesExactGating(t *testing.T) {
+	exact := func(powers []float64) float64 {
+		gatedMean := func(gate float64) float64 {
+			var sum float64
+			var n int
+			for _, p := range powers {
+				if powerToLUFS(p) > gate {
+					sum += p
+					n++
+				}
+			}
+			return sum / float64(n)
+		}
+
+		relative := powerToLUFS(gatedMean(absoluteGate)) + relativeGate
+		return powerToLUFS(gatedMean(max(absoluteGate, relative)))
+	}
+
+	// Windows from -80 to +35 LUFS, including some either side of each gate
+	// and beyond the top of the histogram.
+	var h loudnessHistogram
+	var powers []float64
+	for i := range 1151 {
+		lufs := -80 + 0.1*float64(i) + 0.037
+		p := math.Pow(10, (lufs+0.691)/10)
+		h.add(p)
+		powers = append(powers, p)
+	}
+
+	assertNear(t, "integrated", h.integrated(), exact(powers), histogramStep)
+}
+
+func TestHistogramIgnoresNonFinite(t *testing.T) {
+	var h loudnessHistogram
+	h.add(math.Pow(10, (-23+0.691)/10))
+	h.add(math.NaN())
+	h.add(math.Inf(1))
+
+	// Infinitely loud is as loud as the histogram goes, not a crash.
+	if l := h.integrated(); math.IsNaN(l) {
+		t.Errorf("integrated = %g", l)
+	}
+}
+
+// A full scale sine at a quarter of the sample rate sampled 45 degrees off its
+// peaks never has a sample above 0.707, but peaks at 1 between them.
+func TestTruePeak(t *testing.T) {
+	m := NewMeter(testRate)
+	m.Process(sine(0, float64(testRate)/4, math.Pi/4, 1))
+	l := m.Levels()
+
+	for c := range 2 {
+		assertNear(t, "sample peak", l.Peak[c], -3.01, 0.01)
+		assertNear(t, "true peak", l.TruePeak[c], 0, 0.2)
+	}
+}
+
+func TestReset(t *testing.T) {
+	m := NewMeter(testRate)
+	m.Process(sine(-23, 1000, 0, 1))
+	integrated := m.Levels().Integrated
+
+	m.Reset()
+	l := m.Levels()
+	if !math.IsInf(l.Momentary, -1) || !math.IsInf(l.RMS[0], -1) || !math.IsInf(l.TruePeak[1], -1) {
+		t.Errorf("levels after reset = %+v, want silence", l)
+	}
+	if l.Integrated != integrated {
+		t.Errorf("integrated after reset = %g, want %g kept", l.Integrated, integrated)
+	}
+}
@@ -0,0 +1,314 @@
+// Package spectrum maps the bins of an FFT window onto the frequency bands a
+// visualizer draws.
+package spectrum
+
+import (
+	"fmt"
+	"math"
+	"math/cmplx"
+	"slices"
+
+	"github.com/gopxl/beep"
+)
+
+const (
+	DefaultMinFrequency = 20
+	DefaultMaxFrequency = 20000
+)
+
+// Scale decides how band edges are spread between the minimum and maximum
+// frequency.
+type Scale string
+
+const (
+	// Log spaces bands evenly in log frequency, an equal number per octave.
+	Log Scale = "log"
+	// ThirdOctave uses the standard 1/3 octave bands centred on 1 kHz, so the
+	// number of bands follows from the frequency range.
+	ThirdOctave Scale = "third_octave"
+	// Mel spaces bands evenly in mels.
+	Mel Scale = "mel"
+	// Bark spaces bands evenly on the Bark critical band scale.
+	Bark Scale = "bark"
+)
+
+// Returns the bands+1 edges of bands bands between lo and hi.
+type edgesFunc func(bands int, lo, hi float64) []float64
+
+var scales = map[Scale]edgesFunc{
+	Log:         logEdges,
+	ThirdOctave: thirdOctaveEdges,
+	Mel:         warpedEdges(hzToMel, melToHz),
+	Bark:        warpedEdges(hzToBark, barkToHz),
+}
+
+// Scales lists the names accepted by [ParseScale], sorted.
+func Scales() []string {
+	names := make([]string, 0, len(scales))
+	for s := range scales {
+		names = append(names, string(s))
+	}
+	slices.Sort(names)
+
+	return names
+}
+
+func ParseScale(name string) (Scale, error) {
+	if _, ok := scales[Scale(name)]; !ok {
+		return "", fmt.Errorf("unknown frequency scale %q, expected one of %v", name, Scales())
+	}
+
+	return Scale(name), nil
+}
+
+// Mapper turns FFT windows into band magnitudes. It caches the bin ranges of
+// each band for the last window size it saw, so it is not safe for concurrent
+// use.
+type Mapper struct {
+	bands       int
+	sampleRate  beep.SampleRate
+	scale       Scale
+	minFreq     float64
+	maxFreq     float64
+	interpolate bool
+
+	// Band edges in Hz, bands+1 of them.
+	edges []float64
+	// Bin ranges of each band for windows of fftSize samples.
+	fftSize int
+	ranges  []binRange
+}
+
+// Bins lo through hi inclusive make up a band. A band narrower than a bin has
+// lo > hi and is read at centre instead.
+type binRange struct {
+	lo, hi int
+	centre float64
+}
+
+type MapperOption func(*Mapper)
+
+// WithScale sets how bands are spaced, [Log] by default.
+func WithScale(s Scale) MapperOption {
+	return func(m *Mapper) {
+		m.scale = s
+	}
+}
+
+// WithFrequencyRange limits the bands to between lo and hi Hz, by default
+// [DefaultMinFrequency] to [DefaultMaxFrequency]. hi is capped at the Nyquist
+// frequency.
+func WithFrequencyRange(lo, hi float64) MapperOption {
+	return func(m *Mapper) {
+		m.minFreq = lo
+		m.maxFreq = hi
+	}
+}
+
+// WithInterpolation reads bands narrower than one FFT bin by interpolating
+// between the bins either side of their centre, instead of repeating the
+// nearest bin. On by default.
+func WithInterpolation(interpolate bool) MapperOption {
+	return func(m *Mapper) {
+		m.interpolate = interpolate
+	}
+}
+
+// NewMapper maps FFT windows of audio at sampleRate onto bands bands. With
+// [ThirdOctave] the number of bands is decided by the frequency range instead,
+// see [Mapper.Bands].
+func NewMapper(bands int, sampleRate beep.SampleRate, opts ...MapperOption) (*Mapper, error) {
+	m := &Mapper{
+		bands:       bands,
+		sampleRate:  sampleRate,
+		scale:       Log,
+		minFreq:     DefaultMinFrequency,
+		maxFreq:     DefaultMaxFrequency,
+		interpolate: true,
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	edges, ok := scales[m.scale]
+	if !ok {
+		return nil, fmt.Errorf("unknown frequency scale %q", m.scale)
+	}
+	if m.bands <= 0 {
+		return nil, fmt.Errorf("need at least one band, got %d", m.bands)
+	}
+
+	m.maxFreq = min(m.maxFreq, float64(sampleRate)/2)
+	if m.minFreq <= 0 || m.minFreq >= m.maxFreq {
+		return nil, fmt.Errorf("invalid frequency range %g-%g Hz", m.minFreq, m.maxFreq)
+	}
+
+	m.edges = edges(m.bands, m.minFreq, m.maxFreq)
+	if len(m.edges) < 2 {
+		return nil, fmt.Errorf("no %s bands between %g and %g Hz", m.scale, m.minFreq, m.maxFreq)
+	}
+	m.bands = len(m.edges) - 1
+
+	return m, nil
+}
+
+// Bands is the number of magnitudes [Mapper.Map] returns.
+func (m *Mapper) Bands() int {
+	return m.bands
+}
+
+// Edges returns the bands+1 band edges in Hz.
+func (m *Mapper) Edges() []float64 {
+	return m.edges
+}
+
+// Map returns the amplitude of each band of a full, two sided FFT window: the
+// magnitude of its strongest bin, counting the mirrored negative frequencies.
+// Against the window's coherent gain (see fft.FFTWindow) a full scale sine
+// reads 1 whatever the window function, where summing the power of the bins
+// would also count the sine's leakage into its neighbours and read high.
+func (m *Mapper) Map(fftData []complex128) []float64 {
+	bands := make([]float64, m.bands)
+	if len(fftData) < 2 {
+		return bands
+	}
+	if len(fftData) != m.fftSize {
+		m.computeRanges(len(fftData))
+	}
+
+	for i, r := range m.ranges {
+		if r.lo > r.hi {
+			bands[i] = m.magnitudeAt(fftData, r.centre)
+			continue
+		}
+
+		for k := r.lo; k <= r.hi; k++ {
+			bands[i] = max(bands[i], binMagnitude(fftData, k))
+		}
+	}
+
+	return bands
+}
+
+// PeakBins returns the strongest bin of each band of a full FFT window, or
+// for a band narrower than a bin the nearest one. Quantities like phase only
+// make sense per bin, so this is the bin to read them from.
+func (m *Mapper) PeakBins(fftData []complex128) []int {
+	bins := make([]int, m.bands)
+	if len(fftData) < 2 {
+		return bins
+	}
+	if len(fftData) != m.fftSize {
+		m.computeRanges(len(fftData))
+	}
+
+	for i, r := range m.ranges {
+		if r.lo > r.hi {
+			bins[i] = min(int(math.Round(r.centre)), len(fftData)/2)
+			continue
+		}
+
+		bins[i] = r.lo
+		for k := r.lo + 1; k <= r.hi; k++ {
+			if binMagnitude(fftData, k) > binMagnitude(fftData, bins[i]) {
+				bins[i] = k
+			}
+		}
+	}
+
+	return bins
+}
+
+func (m *Mapper) computeRanges(fftSize int) {
+	m.fftSize = fftSize
+	m.ranges = make([]binRange, m.bands)
+
+	binWidth := float64(m.sampleRate) / float64(fftSize)
+	for i := range m.ranges {
+		lo, hi := m.edges[i]/binWidth, m.edges[i+1]/binWidth
+		m.ranges[i] = binRange{
+			lo:     int(math.Ceil(lo)),
+			hi:     min(int(math.Ceil(hi))-1, fftSize/2),
+			centre: math.Sqrt(lo * hi),
+		}
+	}
+}
+
+// The magnitude at fractional bin k.
+func (m *Mapper) magnitudeAt(fftData []complex128, k float64) float64 {
+	if !m.interpolate {
+		return binMagnitude(fftData, int(math.Round(k)))
+	}
+
+	k0 := int(math.Floor(k))
+	t := k - float64(k0)
+	return (1-t)*binMagnitude(fftData, k0) + t*binMagnitude(fftData, k0+1)
+}
+
+// One sided magnitude of bin k, folding in its negative frequency mirror.
+func binMagnitude(fftData []complex128, k int) float64 {
+	n := len(fftData)
+	k = max(0, min(k, n/2))
+	if k == 0 || 2*k == n {
+		return cmplx.Abs(fftData[k])
+	}
+
+	return cmplx.Abs(fftData[k]) + cmplx.Abs(fftData[n-k])
+}
+
+func logEdges(bands int, lo, hi float64) []float64 {
+	edges := make([]float64, bands+1)
+	for i := range edges {
+		edges[i] = lo * math.Pow(hi/lo, float64(i)/float64(bands))
+	}
+
+	return edges
+}
+
+// Bands centred on 1000 * 2^(n/3) Hz, each 1/3 octave wide, keeping those
+// whose centre is in range.
+func thirdOctaveEdges(_ int, lo, hi float64) []float64 {
+	first := int(math.Ceil(3 * math.Log2(lo/1000)))
+	last := int(math.Floor(3 * math.Log2(hi/1000)))
+
+	var edges []float64
+	for n := first; n <= last; n++ {
+		centre := 1000 * math.Pow(2, float64(n)/3)
+		if n == first {
+			edges = append(edges, centre*math.Pow(2, -1.0/6))
+		}
+		edges = append(edges, centre*math.Pow(2, 1.0/6))
+	}
+
+	return edges
+}
+
+// Edges evenly spaced on the scale toScale maps Hz onto.
+func warpedEdges(toScale, fromScale func(float64) float64) edgesFunc {
+	return func(bands int, lo, hi float64) []float64 {
+		slo, shi := toScale(lo), toScale(hi)
+		edges := make([]float64, bands+1)
+		for i := range edges {
+			edges[i] = fromScale(slo + (shi-slo)*float64(i)/float64(bands))
+		}
+
+		return edges
+	}
+}
+
+func hzToMel(f float64) float64 {
+	return 2595 * math.Log10(1+f/700)
+}
+
+func melToHz(m float64) float64 {
+	return 700 * (math.Pow(10, m/2595) - 1)
+}
+
+// Traunmüller's approximation.
+func hzToBark(f float64) float64 {
+	return 26.81*f/(1960+f) - 0.53
+}
+
+func barkToHz(z float64) float64 {
+	return 1960 * (z + 0.53) / (26.28 - z)
+}
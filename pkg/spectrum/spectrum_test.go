@@ -0,0 +1,147 @@
+package spectrum
+
+import (
+	"math"
+	"testing"
+)
+
+const testRate = 44100
+
+func TestEdges(t *testing.T) {
+	tests := []struct {
+		scale  Scale
+		bands  int
+		lo, hi float64
+		want   []float64
+	}{
+		{Log, 3, 20, 20000, []float64{20, 200, 2000, 20000}},
+		{Log, 2, 100, 400, []float64{100, 200, 400}},
+		// Only the band centred on 1 kHz has its centre in range.
+		// The number of third octave bands follows from the range.
+		{ThirdOctave, 1, 900, 1200, []float64{1000 * math.Pow(2, -1.0/6), 1000 * math.Pow(2, 1.0/6)}},
+		{ThirdOctave, 1, 900, 1300, []float64{
+			1000 * math.Pow(2, -1.0/6), 1000 * math.Pow(2, 1.0/6), 1000 * math.Pow(2, 3.0/6),
+		}},
+		{Mel, 2, 100, 4000, []float64{100, melToHz((hzToMel(100) + hzToMel(4000)) / 2), 4000}},
+		{Bark, 2, 100, 4000, []float64{100, barkToHz((hzToBark(100) + hzToBark(4000)) / 2), 4000}},
+	}
+
+	for _, tt := range tests {
+		m, err := NewMapper(tt.bands, testRate, WithScale(tt.scale), WithFrequencyRange(tt.lo, tt.hi))
+		if err != nil {
+			t.Fatalf("%s: %v", tt.scale, err)
+		}
+
+		edges := m.Edges()
+		if len(edges) != len(tt.want) || m.Bands() != len(tt.want)-1 {
+			t.Errorf("%s %g-%g: edges = %v, want %v", tt.scale, tt.lo, tt.hi, edges, tt.want)
+			continue
+		}
+		for i := range edges {
+			if math.Abs(edges[i]-tt.want[i]) > 1e-6*tt.want[i] {
+				t.Errorf("%s %g-%g: edges = %v, want %v", tt.scale, tt.lo, tt.hi, edges, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestScaleRoundTrips(t *testing.T) {
+	for _, f := range []float64{20, 440, 1000, 8000, 20000} {
+		if got := melToHz(hzToMel(f)); math.Abs(got-f) > 1e-9*f {
+			t.Errorf("mel round trip of %g Hz = %g", f, got)
+		}
+		if got := barkToHz(hzToBark(f)); math.Abs(got-f) > 1e-9*f {
+			t.Errorf("Bark round trip of %g Hz = %g", f, got)
+		}
+	}
+
+	// 1000 mels is 1000 Hz by definition.
+	if got := hzToMel(1000); math.Abs(got-1000) > 0.02 {
+		t.Errorf("1000 Hz = %g mels, want 1000", got)
+	}
+}
+
+func TestThirdOctaveBands(t *testing.T) {
+	// The number of bands asked for is ignored.
+	m, err := NewMapper(1, testRate, WithScale(ThirdOctave))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Centred on 25 Hz to 16 kHz. The exact centres of the nominal 20 Hz and
+	// 20 kHz bands, 19.7 Hz and 20.2 kHz, are out of range.
+	if m.Bands() != 29 {
+		t.Errorf("%d third octave bands in the audible range, want 29", m.Bands())
+	}
+}
+
+func TestEdgesIncrease(t *testing.T) {
+	for _, scale := range Scales() {
+		for _, bands := range []int{1, 8, 64, 256} {
+			m, err := NewMapper(bands, testRate, WithScale(Scale(scale)))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			edges := m.Edges()
+			for i := 1; i < len(edges); i++ {
+				if edges[i] <= edges[i-1] {
+					t.Errorf("%s with %d bands: edge %d %g Hz not above the one before, %g Hz",
+						scale, bands, i, edges[i], edges[i-1])
+				}
+			}
+			if scale != string(ThirdOctave) &&
+				(math.Abs(edges[0]-DefaultMinFrequency) > 1e-9 || math.Abs(edges[len(edges)-1]-DefaultMaxFrequency) > 1e-6) {
+				t.Errorf("%s with %d bands spans %g-%g Hz", scale, bands, edges[0], edges[len(edges)-1])
+			}
+		}
+	}
+}
+
+// Even with many bands narrower than a bin, every band reads something off a
+// spectrum with something in every bin.
+func TestNoEmptyBands(t *testing.T) {
+	for _, scale := range Scales() {
+		for _, interpolate := range []bool{true, false} {
+			for _, fftSize := range []int{64, 256, 1024} {
+				m, err := NewMapper(128, testRate, WithScale(Scale(scale)), WithInterpolation(interpolate))
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				flat := make([]complex128, fftSize)
+				for i := range flat {
+					flat[i] = 1
+				}
+				for i, v := range m.Map(flat) {
+					if v == 0 {
+						t.Errorf("%s, interpolate %v, fft size %d: band %d (%g-%g Hz) is empty",
+							scale, interpolate, fftSize, i, m.Edges()[i], m.Edges()[i+1])
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestNewMapperErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		opts  []MapperOption
+		bands int
+	}{
+		{"no bands", nil, 0},
+		{"unknown scale", []MapperOption{WithScale("linear")}, 8},
+		{"inverted range", []MapperOption{WithFrequencyRange(1000, 100)}, 8},
+		{"zero minimum", []MapperOption{WithFrequencyRange(0, 100)}, 8},
+		{"minimum above Nyquist", []MapperOption{WithFrequencyRange(30000, 40000)}, 8},
+		{"no third octave centre in range", []MapperOption{WithScale(ThirdOctave), WithFrequencyRange(1010, 1020)}, 8},
+	}
+
+	for _, tt := range tests {
+		if _, err := NewMapper(tt.bands, testRate, tt.opts...); err == nil {
+			t.Errorf("%s: no error", tt.name)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package vis
+
+import "strings"
+
+// brailleCanvas draws dots on a grid of braille characters, each cell holding
+// 2x4 dots.
+type brailleCanvas struct {
+	// Size in cells.
+	width  int
+	height int
+	cells  []rune
+}
+
+const brailleBlank = '⠀'
+
+// Bit of each dot within a cell, indexed by [y][x].
+var brailleDots = [4][2]rune{
+	{0x01, 0x08},
+	{0x02, 0x10},
+	{0x04, 0x20},
+	{0x40, 0x80},
+}
+
+func newBrailleCanvas(width, height int) *brailleCanvas {
+	c := &brailleCanvas{
+		width:  width,
+		height: height,
+		cells:  make([]rune, width*height),
+	}
+	for i := range c.cells {
+		c.cells[i] = brailleBlank
+	}
+
+	return c
+}
+
+// Size in dots.
+func (c *brailleCanvas) dotWidth() int  { return 2 * c.width }
+func (c *brailleCanvas) dotHeight() int { return 4 * c.height }
+
+// set turns on the dot at x, y counted from the top left. Dots off the canvas
+// are ignored.
+func (c *brailleCanvas) set(x, y int) {
+	if x < 0 || y < 0 || x >= c.dotWidth() || y >= c.dotHeight() {
+		return
+	}
+
+	c.cells[(y/4)*c.width+x/2] |= brailleDots[y%4][x%2]
+}
+
+// verticalLine turns on the dots in column x from y0 to y1 inclusive.
+func (c *brailleCanvas) verticalLine(x, y0, y1 int) {
+	for y := min(y0, y1); y <= max(y0, y1); y++ {
+		c.set(x, y)
+	}
+}
+
+// rows returns each row of cells as a string.
+func (c *brailleCanvas) rows() []string {
+	rows := make([]string, c.height)
+	for y := range rows {
+		rows[y] = string(c.cells[y*c.width : (y+1)*c.width])
+	}
+
+	return rows
+}
+
+func (c *brailleCanvas) String() string {
+	return strings.Join(c.rows(), "\n")
+}
@@ -0,0 +1,100 @@
+package vis
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/lucasb-eyer/go-colorful"
+	"github.com/muesli/termenv"
+)
+
+// colorMap maps levels between 0 and 1 onto colours, blending evenly spaced
+// stops.
+type colorMap []colorful.Color
+
+// Stops sampled from matplotlib's maps of the same names.
+var colorMaps = map[string]colorMap{
+	"viridis": mustColorMap(
+		"#440154", "#482878", "#3e4a89", "#31688e", "#26828e",
+		"#1f9e89", "#35b779", "#6dcd59", "#b4de2c", "#fde725"),
+	"magma": mustColorMap(
+		"#000004", "#180f3d", "#440f76", "#721f81", "#9e2f7f",
+		"#cd4071", "#f1605d", "#fd9668", "#fec98d", "#fcfdbf"),
+	"grayscale": mustColorMap("#000000", "#ffffff"),
+}
+
+func mustColorMap(hexes ...string) colorMap {
+	m, err := parseColorMap(hexes...)
+	if err != nil {
+		panic(err)
+	}
+
+	return m
+}
+
+func parseColorMap(hexes ...string) (colorMap, error) {
+	m := make(colorMap, len(hexes))
+	for i, h := range hexes {
+		c, err := parseColor(h)
+		if err != nil {
+			return nil, err
+		}
+		m[i] = c
+	}
+
+	return m, nil
+}
+
+func parseColor(hex string) (colorful.Color, error) {
+	c, err := colorful.Hex(hex)
+	if err != nil {
+		return colorful.Color{}, fmt.Errorf("invalid colour %q, expected #rrggbb: %w", hex, err)
+	}
+
+	return c, nil
+}
+
+// colorMapNames lists the names accepted by [lookupColorMap], sorted.
+func colorMapNames() []string {
+	names := make([]string, 0, len(colorMaps))
+	for name := range colorMaps {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	return names
+}
+
+func lookupColorMap(name string) (colorMap, error) {
+	m, ok := colorMaps[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown colour map %q, expected one of %s",
+			name, strings.Join(colorMapNames(), ", "))
+	}
+
+	return m, nil
+}
+
+// at returns the colour for level t, clamped to between 0 and 1.
+func (m colorMap) at(t float64) colorful.Color {
+	t = max(0, min(1, t))
+	if len(m) == 1 {
+		return m[0]
+	}
+
+	pos := t * float64(len(m)-1)
+	i := min(int(pos), len(m)-2)
+	return m[i].BlendLab(m[i+1], pos-float64(i))
+}
+
+// palette samples the map at n evenly spaced levels, as terminal colours.
+func (m colorMap) palette(n int) []termenv.Color {
+	profile := termenv.ColorProfile()
+	p := make([]termenv.Color, n)
+	for i := range p {
+		p[i] = profile.Color(m.at(float64(i) / float64(n-1)).Hex())
+	}
+
+	return p
+}
@@ -0,0 +1,116 @@
+package vis
+
+import (
+	"math"
+	"time"
+)
+
+// How long a peak cap stays put before it starts to fall.
+const peakHold = 250 * time.Millisecond
+
+// barDynamics moves bars towards each new frame's levels over time instead of
+// jumping straight to them, and keeps a cap above each bar at its recent peak.
+type barDynamics struct {
+	// Time constants for bars rising and falling, zero to jump straight there.
+	attack  time.Duration
+	release time.Duration
+	// How fast caps accelerate down in bar lengths per second squared, zero
+	// for no caps.
+	gravity float64
+
+	levels []float64
+	peaks  []peak
+
+	lastUpdate time.Time
+}
+
+type peak struct {
+	level    float64
+	velocity float64
+	// Caps only start to fall once this has passed.
+	heldUntil time.Time
+}
+
+// Implemented by visualizers drawing bars, so the bar options can be given to
+// any visualizer and ignored by the rest.
+type barModel interface {
+	barDynamics() *barDynamics
+}
+
+// update eases the bars towards targets and moves the caps.
+func (d *barDynamics) update(targets []float64) {
+	now := time.Now()
+	dt := now.Sub(d.lastUpdate)
+	d.lastUpdate = now
+
+	if len(d.levels) != len(targets) {
+		// First frame, or the number of bars changed.
+		d.levels = make([]float64, len(targets))
+		d.peaks = make([]peak, len(targets))
+		dt = 0
+	}
+
+	for i, target := range targets {
+		timeConstant := d.release
+		if target > d.levels[i] {
+			timeConstant = d.attack
+		}
+		d.levels[i] += (target - d.levels[i]) * smoothingFactor(dt, timeConstant)
+
+		p := &d.peaks[i]
+		switch {
+		case d.levels[i] >= p.level:
+			*p = peak{level: d.levels[i], heldUntil: now.Add(peakHold)}
+		case now.After(p.heldUntil):
+			p.velocity += d.gravity * dt.Seconds()
+			p.level = max(d.levels[i], p.level-p.velocity*dt.Seconds())
+		}
+	}
+}
+
+// resize sets the number of bars, dropping their levels and caps if it
+// changed, so views drawn before the next frame match the new bands.
+func (d *barDynamics) resize(bars int) {
+	if len(d.levels) == bars {
+		return
+	}
+
+	d.levels = make([]float64, bars)
+	d.peaks = make([]peak, bars)
+}
+
+// showPeaks reports whether caps should be drawn.
+func (d *barDynamics) showPeaks() bool {
+	return d.gravity > 0 && len(d.peaks) == len(d.levels)
+}
+
+// The fraction of the way to its target a bar moves in dt, for an exponential
+// approach with the given time constant.
+func smoothingFactor(dt, timeConstant time.Duration) float64 {
+	if timeConstant <= 0 {
+		return 1
+	}
+
+	return 1 - math.Exp(-dt.Seconds()/timeConstant.Seconds())
+}
+
+// WithSmoothing eases bars up to louder levels over attack and back down over
+// release, instead of jumping to every frame's level.
+func WithSmoothing(attack, release time.Duration) VisualizerOption {
+	return func(v GoldsmithModel) {
+		if b, ok := v.(barModel); ok {
+			b.barDynamics().attack = attack
+			b.barDynamics().release = release
+		}
+	}
+}
+
+// WithPeakCaps draws a cap at each bar's recent peak, which after a short hold
+// falls with gravity, in bar lengths per second squared. Zero turns caps off.
+func WithPeakCaps(gravity float64) VisualizerOption {
+	return func(v GoldsmithModel) {
+		if b, ok := v.(barModel); ok {
+			b.barDynamics().gravity = gravity
+		}
+	}
+}
@@ -0,0 +1,232 @@
+package vis
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/muesli/termenv"
+)
+
+func init() {
+	Register("goniometer", "Stereo vectorscope of left/right sample pairs with a correlation meter",
+		[]OptionSpec{
+			{Name: "size", Description: "Width in characters, the height is half of it", Default: "60"},
+			{Name: "gain", Description: "Zoom, 1 fits a full scale signal", Default: "1"},
+			{Name: "persistence", Description: "How long plotted samples take to fade, like a phosphor screen", Default: "150ms"},
+		},
+		func(cfg Config, opts ...VisualizerOption) (Visualizer, error) {
+			size, err := cfg.Int("size")
+			if err != nil {
+				return nil, err
+			}
+			if size < 2 {
+				return nil, fmt.Errorf("size must be at least 2, got %d", size)
+			}
+			gain, err := cfg.Float("gain")
+			if err != nil {
+				return nil, err
+			}
+			persistence, err := cfg.Duration("persistence")
+			if err != nil {
+				return nil, err
+			}
+
+			return NewGoniometerVisualizer(size, gain, persistence, opts...), nil
+		})
+}
+
+// Number of brightness levels plotted dots are drawn with.
+const phosphorShades = 16
+
+// Dots fainter than this are not drawn.
+const phosphorThreshold = 0.05
+
+var phosphor = mustColorMap("#0b3d0b", "#39ff14", "#e0ffe0")
+
+type GoniometerVisualizer struct {
+	VisualizerShared
+	program *tea.Program
+}
+
+func (v GoniometerVisualizer) UpdateVisualizer(newFFTData NewFFTData) {
+	v.program.Send(newFFTData)
+}
+
+type GoniometerModel struct {
+	GoldsmithSharedFields
+
+	// Size in characters.
+	width  int
+	height int
+	gain   float64
+	// Time constant of the fade.
+	persistence time.Duration
+
+	// Brightness of each braille dot, row by row.
+	screen      []float64
+	correlation float64
+	lastUpdate  time.Time
+
+	palette []termenv.Color
+}
+
+func NewGoniometerVisualizer(size int, gain float64, persistence time.Duration, opts ...VisualizerOption) *GoniometerVisualizer {
+	// Braille dots are about twice as tall as they are wide, and a cell twice
+	// as tall as it is wide, so this makes the plot square.
+	width, height := size, size/2
+
+	m := GoniometerModel{
+		width:                 width,
+		height:                height,
+		gain:                  gain,
+		persistence:           persistence,
+		screen:                make([]float64, 2*width*4*height),
+		palette:               phosphor.palette(phosphorShades),
+		GoldsmithSharedFields: initSharedFields(defaultKeymap),
+	}
+
+	p, doneChan := launchTeaProgram(&m, opts)
+
+	return &GoniometerVisualizer{
+		program:          p,
+		VisualizerShared: VisualizerShared{done: doneChan},
+	}
+}
+
+func (m GoniometerModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m GoniometerModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case NewFFTData:
+		if msg.Done {
+			return m, tea.Quit
+		}
+
+		m.updateFPS()
+		m.fade()
+		m.plot(msg.Samples)
+		m.correlation = correlation(msg.Samples)
+		return m, nil
+
+	case tea.KeyMsg:
+		return m.handleKey(msg)
+
+	case tea.MouseMsg:
+		return m, m.handleMouse(msg)
+
+	case tea.WindowSizeMsg:
+		m.setWindowSize(msg)
+		return m, nil
+	}
+	return m, nil
+}
+
+func (m GoniometerModel) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case key.Matches(msg, m.keymap.quit):
+		return m, tea.Quit
+	}
+
+	return m, m.handlePlaybackKey(msg)
+}
+
+// Dims everything plotted so far by how long it has been since the last frame.
+func (m *GoniometerModel) fade() {
+	now := time.Now()
+	dt := now.Sub(m.lastUpdate)
+	m.lastUpdate = now
+
+	decay := 1 - smoothingFactor(dt, m.persistence)
+	for i := range m.screen {
+		m.screen[i] *= decay
+	}
+}
+
+// Plots each sample rotated 45 degrees, so the mid signal is vertical and the
+// side signal horizontal: mono is a vertical line, out of phase audio a
+// horizontal one.
+func (m *GoniometerModel) plot(samples [][2]float64) {
+	dotWidth, dotHeight := 2*m.width, 4*m.height
+	for _, s := range samples {
+		// A true 45 degree rotation divides by √2, halving instead keeps
+		// full scale audio within ±1.
+		side := (s[0] - s[1]) / 2 * m.gain
+		mid := (s[0] + s[1]) / 2 * m.gain
+
+		x := int(math.Round((side + 1) / 2 * float64(dotWidth-1)))
+		y := int(math.Round((1 - mid) / 2 * float64(dotHeight-1)))
+		if x < 0 || y < 0 || x >= dotWidth || y >= dotHeight {
+			continue
+		}
+		m.screen[y*dotWidth+x] = 1
+	}
+}
+
+// Pearson correlation between the channels, +1 for mono, -1 for one channel
+// inverted and 0 for unrelated channels or silence.
+func correlation(samples [][2]float64) float64 {
+	var lr, ll, rr float64
+	for _, s := range samples {
+		lr += s[0] * s[1]
+		ll += s[0] * s[0]
+		rr += s[1] * s[1]
+	}
+	if ll == 0 || rr == 0 {
+		return 0
+	}
+
+	return lr / math.Sqrt(ll*rr)
+}
+
+func (m GoniometerModel) View() string {
+	canvas := newBrailleCanvas(m.width, m.height)
+	dotWidth := canvas.dotWidth()
+
+	// Each cell is as bright as its brightest dot.
+	brightness := make([]float64, m.width*m.height)
+	for i, v := range m.screen {
+		if v < phosphorThreshold {
+			continue
+		}
+		x, y := i%dotWidth, i/dotWidth
+		canvas.set(x, y)
+		cell := (y/4)*m.width + x/2
+		brightness[cell] = max(brightness[cell], v)
+	}
+
+	var b strings.Builder
+	for y, row := range canvas.rows() {
+		for x, r := range []rune(row) {
+			shade := int(brightness[y*m.width+x] * float64(phosphorShades-1))
+			b.WriteString(termenv.String(string(r)).Foreground(m.palette[shade]).String())
+		}
+		b.WriteRune('\n')
+	}
+	b.WriteString(m.correlationMeter())
+	b.WriteRune('\n')
+
+	if m.showFPS {
+		displayFPS(&b, m.GoldsmithSharedFields)
+	}
+
+	return m.withFooter(b.String())
+}
+
+// A marker on a -1 to +1 scale as wide as the plot, and the value.
+func (m GoniometerModel) correlationMeter() string {
+	const labels = "-1  +1 +0.00"
+	track := max(3, m.width-len(labels))
+
+	marker := int(math.Round((m.correlation + 1) / 2 * float64(track-1)))
+	meter := []rune(strings.Repeat("─", track))
+	meter[track/2] = '┼'
+	meter[marker] = '●'
+
+	return fmt.Sprintf("-1 %s +1 %+.2f", string(meter), m.correlation)
+}
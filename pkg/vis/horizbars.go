@@ -2,14 +2,47 @@ package vis
 
 import (
 	"fmt"
-	"math"
-	"math/cmplx"
 	"slices"
 	"strings"
 
 	"github.com/charmbracelet/bubbles/key"
-	"github.com/charmbracelet/bubbles/progress"
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/muesli/termenv"
+)
+
+func init() {
+	Register("horizontal_bars", "One horizontal bar per frequency band, low frequencies at the top",
+		slices.Concat([]OptionSpec{
+			{Name: "bars", Description: "Number of bars, or auto for one per line", Default: Auto},
+			{Name: "width", Description: "Length of the bars in characters, or auto to fit the terminal width", Default: Auto},
+		}, spectrumOptions, levelOptions, themeOptions),
+		func(cfg Config, opts ...VisualizerOption) (Visualizer, error) {
+			numBars, err := cfg.IntOrAuto("bars")
+			if err != nil {
+				return nil, err
+			}
+			width, err := cfg.IntOrAuto("width")
+			if err != nil {
+				return nil, err
+			}
+			scale, err := levelScaleFromConfig(cfg)
+			if err != nil {
+				return nil, err
+			}
+			colors, err := barColorsFromConfig(cfg)
+			if err != nil {
+				return nil, err
+			}
+
+			return NewHorizontalBarsVisualizer(bandMapperFromConfig(cfg), scale, numBars, width,
+				colors, opts...)
+		})
+}
+
+// Used with bars=auto and width=auto until the terminal size is known.
+const (
+	defaultHorizontalBars  = 32
+	defaultHorizontalWidth = 40
 )
 
 type HorizontalBarsVisualizer struct {
@@ -23,19 +56,59 @@ func (v HorizontalBarsVisualizer) UpdateVisualizer(newFFTData NewFFTData) {
 
 type HorizontalBarsModel struct {
 	GoldsmithSharedFields
-	fftData      []complex128
-	numBars      int
-	bar          progress.Model
-	maxBarHeight int
+	mapper   resizableMapper
+	scale    *LevelScale
+	dynamics barDynamics
+	// Bars and bar length asked for, 0 to fit the terminal.
+	numBars int
+	width   int
+	// Actual bar length in characters.
+	barWidth int
+
+	theme   barTheme
+	phase   phaseColors
+	Empty   rune
+	Full    rune
+	PeakCap rune
 }
 
-func NewHorizontalBarsVisualizer(numBars int, maxBarHeight int, opts ...VisualizerOption) *HorizontalBarsVisualizer {
-	bar := progress.New(progress.WithDefaultGradient())
+// NewHorizontalBarsVisualizer draws numBars bars barWidth characters long.
+// With either 0 it is fit to the terminal instead.
+func NewHorizontalBarsVisualizer(
+	newMapper BandMapper,
+	scale *LevelScale,
+	numBars int,
+	barWidth int,
+	colors BarColors,
+	opts ...VisualizerOption,
+) (*HorizontalBarsVisualizer, error) {
+	initialBars, initialWidth := numBars, barWidth
+	if initialBars == 0 {
+		initialBars = defaultHorizontalBars
+	}
+	if initialWidth == 0 {
+		initialWidth = defaultHorizontalWidth
+	}
+	mapper, err := newResizableMapper(newMapper, initialBars)
+	if err != nil {
+		return nil, err
+	}
+	theme, err := newBarTheme(colors.Theme, colors.Gradient)
+	if err != nil {
+		return nil, err
+	}
 
 	m := HorizontalBarsModel{
-		bar:                   bar,
+		mapper:                mapper,
+		scale:                 scale,
 		numBars:               numBars,
-		maxBarHeight:          maxBarHeight,
+		width:                 barWidth,
+		barWidth:              initialWidth,
+		theme:                 theme,
+		phase:                 newPhaseColors(colors.Phase),
+		Full:                  '█',
+		Empty:                 '░',
+		PeakCap:               '▏',
 		GoldsmithSharedFields: initSharedFields(defaultKeymap),
 	}
 
@@ -44,7 +117,11 @@ func NewHorizontalBarsVisualizer(numBars int, maxBarHeight int, opts ...Visualiz
 	return &HorizontalBarsVisualizer{
 		program:          p,
 		VisualizerShared: VisualizerShared{done: doneChan},
-	}
+	}, nil
+}
+
+func (m *HorizontalBarsModel) barDynamics() *barDynamics {
+	return &m.dynamics
 }
 
 func (m HorizontalBarsModel) Init() tea.Cmd {
@@ -59,52 +136,111 @@ func (m HorizontalBarsModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 
 		m.updateFPS()
-		m.fftData = msg.Data
+		m.dynamics.update(m.scale.Levels(m.mapper.Map(msg.Data), msg.Gain))
+		m.phase.update(m.mapper.Mapper, msg.Offset, msg.Data)
 		return m, nil
 
 	case tea.KeyMsg:
 		return m.handleKey(msg)
+
+	case tea.MouseMsg:
+		return m, m.handleMouse(msg)
+
+	case tea.WindowSizeMsg:
+		m.setWindowSize(msg)
+		m.fitToWindow()
+		return m, nil
 	}
 	return m, nil
 }
 
+// Gives every line a bar and makes the bars as long as fits beside their
+// labels, unless those are fixed.
+func (m *HorizontalBarsModel) fitToWindow() {
+	width, height := m.bodySize()
+	if width == 0 || height == 0 {
+		return
+	}
+
+	if m.numBars == 0 {
+		m.mapper.resize(height)
+		m.dynamics.resize(m.mapper.Bands())
+	}
+	if m.width == 0 {
+		m.barWidth = max(1, width-labelWidth(bandLabels(m.mapper.Edges()))-1)
+	}
+}
+
 func (m HorizontalBarsModel) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	switch {
 	case key.Matches(msg, m.keymap.quit):
 		return m, tea.Quit
 	}
 
-	return m, nil
+	return m, m.handlePlaybackKey(msg)
 }
 
 func (m HorizontalBarsModel) View() string {
-	aggregateBars := make([]float64, m.numBars)
-
-	// First aggregate bars together from fft window to match requested number of bars.
-	// And also convert to logarithmic scale.
-	// Divide by 2 to combine the negative frequency components.
-	barsToAggregate := len(m.fftData) / m.numBars / 2
-	for bi := range m.numBars {
-		for i := range barsToAggregate {
-			posComponent := cmplx.Abs(m.fftData[bi*barsToAggregate+i])
-			negComponent := cmplx.Abs(m.fftData[len(m.fftData)-1-bi*barsToAggregate-i])
-			aggregateBars[bi] += posComponent + negComponent
+	var sb strings.Builder
+
+	labels := bandLabels(m.mapper.Edges())
+	width := labelWidth(labels)
+	showPeaks := m.dynamics.showPeaks()
+	for i, level := range m.dynamics.levels {
+		fmt.Fprintf(&sb, "%*s ", width, labels[i])
+
+		filled := int(level * float64(m.barWidth))
+		capAt := int(m.dynamics.peaks[i].level * float64(m.barWidth))
+		for x := range m.barWidth {
+			switch {
+			case x < filled:
+				color := m.theme.fillColor(i, len(m.dynamics.levels), x, m.barWidth)
+				if m.phase.enabled() {
+					color = m.phase.color(i)
+				}
+				sb.WriteString(termenv.String(string(m.Full)).Foreground(color).String())
+			case showPeaks && x == capAt:
+				sb.WriteString(termenv.String(string(m.PeakCap)).Foreground(m.theme.peak).String())
+			default:
+				sb.WriteString(termenv.String(string(m.Empty)).Foreground(m.theme.empty).String())
+			}
 		}
-		aggregateBars[bi] = math.Log1p(aggregateBars[bi])
+		sb.WriteRune('\n')
 	}
-	maxComponent := slices.Max(aggregateBars)
-	for i := range m.numBars {
-		aggregateBars[i] /= maxComponent
+
+	if m.showFPS {
+		displayFPS(&sb, m.GoldsmithSharedFields)
 	}
 
-	var sb strings.Builder
-	for _, barValue := range aggregateBars {
-		fmt.Fprintf(&sb, "%s\n", m.bar.ViewAs(barValue))
+	return m.withFooter(sb.String())
+}
+
+// The frequency range of each band, as "lo-hi" in Hz.
+func bandLabels(edges []float64) []string {
+	labels := make([]string, len(edges)-1)
+	for i := range labels {
+		labels[i] = formatBandEdge(edges[i]) + "-" + formatBandEdge(edges[i+1])
 	}
 
-	if m.showFPS {
-		displayFPS(&sb, m.GoldsmithSharedFields)
+	return labels
+}
+
+func labelWidth(labels []string) int {
+	var width int
+	for _, l := range labels {
+		width = max(width, len(l))
+	}
+
+	return width
+}
+
+// Like formatFrequency, to three significant figures since band edges are
+// rarely round.
+func formatBandEdge(f float64) string {
+	// Anything that rounds to 1000.
+	if f >= 999.5 {
+		return fmt.Sprintf("%.3gk", f/1000)
 	}
 
-	return sb.String()
+	return fmt.Sprintf("%.3g", f)
 }
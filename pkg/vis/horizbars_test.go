@@ -0,0 +1,58 @@
+package vis
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/brandonpollack23/goldsmith/pkg/spectrum"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func newTestHorizontalBars(t *testing.T) HorizontalBarsModel {
+	t.Helper()
+
+	mapper, err := newResizableMapper(func(bands int) (*spectrum.Mapper, error) {
+		return spectrum.NewMapper(bands, 44100)
+	}, defaultHorizontalBars)
+	if err != nil {
+		t.Fatal(err)
+	}
+	scale, err := NewLevelScale(-90, 0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	theme, err := newBarTheme(themes["default"], HeightGradient)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := HorizontalBarsModel{
+		mapper:                mapper,
+		scale:                 scale,
+		barWidth:              defaultHorizontalWidth,
+		theme:                 theme,
+		phase:                 newPhaseColors(NoPhase),
+		Full:                  '█',
+		Empty:                 '░',
+		PeakCap:               '▏',
+		GoldsmithSharedFields: initSharedFields(defaultKeymap),
+	}
+	WithPeakCaps(10)(&m)
+
+	return m
+}
+
+// Resizing rebuilds the bands before the next frame arrives, which a paused
+// track might never send.
+func TestHorizontalBarsResizeBetweenFrames(t *testing.T) {
+	for _, size := range []struct{ before, after int }{{40, 10}, {10, 40}} {
+		var model tea.Model = newTestHorizontalBars(t)
+		model, _ = model.Update(tea.WindowSizeMsg{Width: 100, Height: size.before})
+		model, _ = model.Update(NewFFTData{Data: make([]complex128, 2048), Gain: 1})
+		model, _ = model.Update(tea.WindowSizeMsg{Width: 100, Height: size.after})
+
+		if lines := strings.Count(model.View(), "\n"); lines != size.after {
+			t.Errorf("resized from %d to %d lines, drew %d bars", size.before, size.after, lines)
+		}
+	}
+}
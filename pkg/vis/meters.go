@@ -0,0 +1,254 @@
+package vis
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/brandonpollack23/goldsmith/pkg/loudness"
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/muesli/termenv"
+)
+
+func init() {
+	Register("meters", "RMS, peak and true peak meters per channel and EBU R128 loudness",
+		[]OptionSpec{
+			{Name: "width", Description: "Width of the meters in characters", Default: "60"},
+			{Name: "floor", Description: "Level in dB at the left end of the meters", Default: "-60"},
+		},
+		func(cfg Config, opts ...VisualizerOption) (Visualizer, error) {
+			width, err := cfg.Int("width")
+			if err != nil {
+				return nil, err
+			}
+			if width < 1 {
+				return nil, fmt.Errorf("width must be at least 1, got %d", width)
+			}
+			floor, err := cfg.Float("floor")
+			if err != nil {
+				return nil, err
+			}
+			if floor >= 0 {
+				return nil, fmt.Errorf("floor must be below 0 dB, got %g", floor)
+			}
+
+			return NewMetersVisualizer(width, floor, opts...), nil
+		})
+}
+
+const (
+	// How long the peak hold marker stays before falling.
+	meterPeakHold = 2 * time.Second
+	// How fast peak meters fall, the 20dB in 1.7s of a PPM.
+	meterPeakFall = 20 / 1.7 // dB per second
+)
+
+// Colour zones of a meter: below warn is safe, above danger is too loud.
+type meterZones struct {
+	warn   float64
+	danger float64
+}
+
+var (
+	// 0 VU aligned to -18 dBFS.
+	rmsZones = meterZones{warn: -18, danger: -9}
+	// Those of a digital peak programme meter.
+	peakZones = meterZones{warn: -18, danger: -6}
+	// EBU R128 allows at most -1 dBTP.
+	truePeakZones = meterZones{warn: -6, danger: -1}
+	// Around and above EBU R128's -23 LUFS target.
+	loudnessZones = meterZones{warn: -23, danger: -18}
+)
+
+type MetersVisualizer struct {
+	VisualizerShared
+	program *tea.Program
+}
+
+func (v MetersVisualizer) UpdateVisualizer(newFFTData NewFFTData) {
+	v.program.Send(newFFTData)
+}
+
+type MetersModel struct {
+	GoldsmithSharedFields
+	levels loudness.Levels
+
+	// Sample peak and true peak of each channel, falling like a PPM.
+	peaks     [2]peakMeter
+	truePeaks [2]peakMeter
+
+	// Width of the meters in characters, and the level they start at.
+	width int
+	floor float64
+
+	SafeColor   string
+	WarnColor   string
+	DangerColor string
+	EmptyColor  string
+	HoldColor   string
+}
+
+// A meter that rises at once, falls at meterPeakFall down to the bottom of the
+// scale and holds its highest level for meterPeakHold.
+type peakMeter struct {
+	level     float64
+	hold      float64
+	heldUntil time.Time
+}
+
+func (p *peakMeter) update(level, floor float64, now time.Time, dt time.Duration) {
+	fallen := max(p.level-meterPeakFall*dt.Seconds(), floor)
+	p.level = max(level, min(p.level, fallen))
+	if level >= p.hold || now.After(p.heldUntil) {
+		p.hold = p.level
+		p.heldUntil = now.Add(meterPeakHold)
+	}
+}
+
+func NewMetersVisualizer(width int, floor float64, opts ...VisualizerOption) *MetersVisualizer {
+	silent := peakMeter{level: math.Inf(-1), hold: math.Inf(-1)}
+	m := MetersModel{
+		levels:                loudness.Silence(),
+		peaks:                 [2]peakMeter{silent, silent},
+		truePeaks:             [2]peakMeter{silent, silent},
+		width:                 width,
+		floor:                 floor,
+		SafeColor:             "#04B575",
+		WarnColor:             "#FFBF00",
+		DangerColor:           "#FF4672",
+		EmptyColor:            "#3A3A3A",
+		HoldColor:             "#FFFFFF",
+		GoldsmithSharedFields: initSharedFields(defaultKeymap),
+	}
+
+	p, doneChan := launchTeaProgram(&m, opts)
+
+	return &MetersVisualizer{
+		program:          p,
+		VisualizerShared: VisualizerShared{done: doneChan},
+	}
+}
+
+func (m MetersModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m MetersModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case NewFFTData:
+		if msg.Done {
+			return m, tea.Quit
+		}
+
+		now := time.Now()
+		dt := now.Sub(m.lastFrameTime)
+		m.updateFPS()
+
+		m.levels = msg.Levels
+		for c := range 2 {
+			m.peaks[c].update(msg.Levels.Peak[c], m.floor, now, dt)
+			m.truePeaks[c].update(msg.Levels.TruePeak[c], m.floor, now, dt)
+		}
+		return m, nil
+
+	case tea.KeyMsg:
+		return m.handleKey(msg)
+
+	case tea.MouseMsg:
+		return m, m.handleMouse(msg)
+
+	case tea.WindowSizeMsg:
+		m.setWindowSize(msg)
+		return m, nil
+	}
+	return m, nil
+}
+
+func (m MetersModel) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case key.Matches(msg, m.keymap.quit):
+		return m, tea.Quit
+	}
+
+	return m, m.handlePlaybackKey(msg)
+}
+
+func (m MetersModel) View() string {
+	var b strings.Builder
+	noHold := math.Inf(-1)
+
+	for c, name := range []string{"L", "R"} {
+		m.writeMeter(&b, name+" RMS", m.levels.RMS[c], noHold, rmsZones, "dBFS")
+	}
+	for c, name := range []string{"L", "R"} {
+		m.writeMeter(&b, name+" Peak", m.peaks[c].level, m.peaks[c].hold, peakZones, "dBFS")
+	}
+	for c, name := range []string{"L", "R"} {
+		m.writeMeter(&b, name+" TP", m.truePeaks[c].level, m.truePeaks[c].hold, truePeakZones, "dBTP")
+	}
+	b.WriteRune('\n')
+	m.writeMeter(&b, "M", m.levels.Momentary, noHold, loudnessZones, "LUFS")
+	m.writeMeter(&b, "S", m.levels.ShortTerm, noHold, loudnessZones, "LUFS")
+	m.writeMeter(&b, "I", m.levels.Integrated, noHold, loudnessZones, "LUFS")
+
+	if m.showFPS {
+		displayFPS(&b, m.GoldsmithSharedFields)
+	}
+
+	return m.withFooter(b.String())
+}
+
+// Writes a labelled meter filled up to level, with a marker at hold, and the
+// level as a number.
+func (m MetersModel) writeMeter(b *strings.Builder, label string, level, hold float64, zones meterZones, unit string) {
+	fmt.Fprintf(b, "%-7s", label)
+
+	filled := m.cells(level)
+	holdAt := m.cells(hold) - 1
+	for i := range m.width {
+		// The level at the right edge of this cell.
+		db := m.floor - m.floor*float64(i+1)/float64(m.width)
+
+		switch {
+		case i < filled:
+			b.WriteString(termenv.String("█").Foreground(m.color(m.zoneColor(db, zones))).String())
+		case i == holdAt:
+			b.WriteString(termenv.String("▌").Foreground(m.color(m.HoldColor)).String())
+		default:
+			b.WriteString(termenv.String("█").Foreground(m.color(m.EmptyColor)).String())
+		}
+	}
+
+	fmt.Fprintf(b, " %6s %s\n", formatDB(level), unit)
+}
+
+// Number of cells a level fills.
+func (m MetersModel) cells(db float64) int {
+	fraction := (db - m.floor) / -m.floor
+	return int(math.Round(max(0, min(1, fraction)) * float64(m.width)))
+}
+
+func (m MetersModel) zoneColor(db float64, zones meterZones) string {
+	switch {
+	case db > zones.danger:
+		return m.DangerColor
+	case db > zones.warn:
+		return m.WarnColor
+	default:
+		return m.SafeColor
+	}
+}
+
+func (m MetersModel) color(c string) termenv.Color {
+	return termenv.ColorProfile().Color(c)
+}
+
+func formatDB(db float64) string {
+	if math.IsInf(db, -1) {
+		return "-inf"
+	}
+
+	return fmt.Sprintf("%.1f", db)
+}
@@ -0,0 +1,200 @@
+package vis
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/muesli/termenv"
+)
+
+func init() {
+	Register("oscilloscope", "The waveform of each window drawn in braille",
+		[]OptionSpec{
+			{Name: "width", Description: "Width in characters", Default: "80"},
+			{Name: "height", Description: "Height in lines", Default: "20"},
+			{Name: "channel", Description: "Signal to draw: mid, left, right or side", Default: "mid"},
+			{Name: "gain", Description: "Vertical zoom, 1 fits a full scale signal", Default: "1"},
+			{Name: "trigger", Description: "Start each frame on a rising zero crossing so periodic waves stand still", Default: "true"},
+		},
+		func(cfg Config, opts ...VisualizerOption) (Visualizer, error) {
+			width, err := cfg.Int("width")
+			if err != nil {
+				return nil, err
+			}
+			height, err := cfg.Int("height")
+			if err != nil {
+				return nil, err
+			}
+			if width < 1 || height < 1 {
+				return nil, fmt.Errorf("width and height must be at least 1, got %dx%d", width, height)
+			}
+			channel, ok := scopeChannels[cfg.String("channel")]
+			if !ok {
+				return nil, fmt.Errorf("unknown channel %q, expected mid, left, right or side", cfg.String("channel"))
+			}
+			gain, err := cfg.Float("gain")
+			if err != nil {
+				return nil, err
+			}
+			trigger, err := cfg.Bool("trigger")
+			if err != nil {
+				return nil, err
+			}
+
+			return NewOscilloscopeVisualizer(width, height, channel, gain, trigger, opts...), nil
+		})
+}
+
+// ScopeChannel picks the signal an oscilloscope draws from a stereo sample.
+type ScopeChannel func(sample [2]float64) float64
+
+var scopeChannels = map[string]ScopeChannel{
+	"mid":   func(s [2]float64) float64 { return (s[0] + s[1]) / 2 },
+	"left":  func(s [2]float64) float64 { return s[0] },
+	"right": func(s [2]float64) float64 { return s[1] },
+	"side":  func(s [2]float64) float64 { return (s[0] - s[1]) / 2 },
+}
+
+type OscilloscopeVisualizer struct {
+	VisualizerShared
+	program *tea.Program
+}
+
+func (v OscilloscopeVisualizer) UpdateVisualizer(newFFTData NewFFTData) {
+	v.program.Send(newFFTData)
+}
+
+type OscilloscopeModel struct {
+	GoldsmithSharedFields
+	samples []float64
+
+	// Size in characters.
+	width   int
+	height  int
+	channel ScopeChannel
+	gain    float64
+	trigger bool
+
+	Color string
+}
+
+func NewOscilloscopeVisualizer(
+	width, height int,
+	channel ScopeChannel,
+	gain float64,
+	trigger bool,
+	opts ...VisualizerOption,
+) *OscilloscopeVisualizer {
+	m := OscilloscopeModel{
+		width:                 width,
+		height:                height,
+		channel:               channel,
+		gain:                  gain,
+		trigger:               trigger,
+		Color:                 "#7571F9",
+		GoldsmithSharedFields: initSharedFields(defaultKeymap),
+	}
+
+	p, doneChan := launchTeaProgram(&m, opts)
+
+	return &OscilloscopeVisualizer{
+		program:          p,
+		VisualizerShared: VisualizerShared{done: doneChan},
+	}
+}
+
+func (m OscilloscopeModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m OscilloscopeModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case NewFFTData:
+		if msg.Done {
+			return m, tea.Quit
+		}
+
+		m.updateFPS()
+		m.samples = m.frame(msg.Samples)
+		return m, nil
+
+	case tea.KeyMsg:
+		return m.handleKey(msg)
+
+	case tea.MouseMsg:
+		return m, m.handleMouse(msg)
+
+	case tea.WindowSizeMsg:
+		m.setWindowSize(msg)
+		return m, nil
+	}
+	return m, nil
+}
+
+func (m OscilloscopeModel) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case key.Matches(msg, m.keymap.quit):
+		return m, tea.Quit
+	}
+
+	return m, m.handlePlaybackKey(msg)
+}
+
+// The part of a window to draw: half of it, starting at the first rising zero
+// crossing in the other half if triggering.
+func (m OscilloscopeModel) frame(samples [][2]float64) []float64 {
+	signal := make([]float64, len(samples))
+	for i, s := range samples {
+		signal[i] = m.channel(s)
+	}
+
+	span := len(signal) / 2
+	start := 0
+	if m.trigger {
+		for i := 1; i <= len(signal)-span; i++ {
+			if signal[i-1] < 0 && signal[i] >= 0 {
+				start = i
+				break
+			}
+		}
+	}
+
+	return signal[start : start+span]
+}
+
+func (m OscilloscopeModel) View() string {
+	canvas := newBrailleCanvas(m.width, m.height)
+
+	dotWidth, dotHeight := canvas.dotWidth(), canvas.dotHeight()
+	if len(m.samples) > 0 {
+		prevY := -1
+		for x := range dotWidth {
+			v := m.samples[x*len(m.samples)/dotWidth] * m.gain
+			// +1 at the top, -1 at the bottom.
+			y := int((1 - v) / 2 * float64(dotHeight-1))
+			y = max(0, min(dotHeight-1, y))
+
+			// Join each point to the last so steep edges stay connected.
+			if prevY < 0 {
+				prevY = y
+			}
+			canvas.verticalLine(x, prevY, y)
+			prevY = y
+		}
+	}
+
+	var b strings.Builder
+	color := termenv.ColorProfile().Color(m.Color)
+	for _, row := range canvas.rows() {
+		b.WriteString(termenv.String(row).Foreground(color).String())
+		b.WriteRune('\n')
+	}
+
+	if m.showFPS {
+		displayFPS(&b, m.GoldsmithSharedFields)
+	}
+
+	return m.withFooter(b.String())
+}
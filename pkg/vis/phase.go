@@ -0,0 +1,123 @@
+package vis
+
+import (
+	"fmt"
+	"math"
+	"math/cmplx"
+
+	"github.com/brandonpollack23/goldsmith/pkg/spectrum"
+	"github.com/lucasb-eyer/go-colorful"
+	"github.com/muesli/termenv"
+)
+
+// Number of hues around the colour wheel phases are mapped onto.
+const phaseShades = 36
+
+// PhaseMode is what, if anything, bars are coloured by in place of their
+// theme's gradient. Phase is read from the strongest bin of each band.
+type PhaseMode string
+
+const (
+	// NoPhase keeps the theme's gradient.
+	NoPhase PhaseMode = "off"
+	// AbsolutePhase maps the phase of each band to hue.
+	AbsolutePhase PhaseMode = "phase"
+	// PhaseChange maps how far each band's phase moved since the last
+	// window, beyond the advance its frequency accounts for, to hue. Steady
+	// tones keep a steady hue while transients and noise flicker.
+	PhaseChange PhaseMode = "change"
+)
+
+func parsePhaseMode(name string) (PhaseMode, error) {
+	switch p := PhaseMode(name); p {
+	case NoPhase, AbsolutePhase, PhaseChange:
+		return p, nil
+	default:
+		return "", fmt.Errorf("unknown phase colouring %q, expected off, phase or change", name)
+	}
+}
+
+// phaseColors colours bands by phase.
+type phaseColors struct {
+	mode    PhaseMode
+	palette []termenv.Color
+
+	// Spectra of the last window and where it started, for PhaseChange.
+	previous       [][]complex128
+	previousOffset int
+
+	// Hue of each band from 0 to 1, the bands of each spectrum in turn.
+	hues []float64
+}
+
+func newPhaseColors(mode PhaseMode) phaseColors {
+	profile := termenv.ColorProfile()
+	palette := make([]termenv.Color, phaseShades)
+	for i := range palette {
+		palette[i] = profile.Color(colorful.Hsv(360*float64(i)/phaseShades, 0.75, 1).Hex())
+	}
+
+	return phaseColors{mode: mode, palette: palette}
+}
+
+func (p phaseColors) enabled() bool {
+	return p.mode != NoPhase
+}
+
+// update reads the phase of every band of the spectra of the window starting
+// at offset, which must be passed in the same order every time.
+func (p *phaseColors) update(mapper *spectrum.Mapper, offset int, spectra ...[]complex128) {
+	if !p.enabled() {
+		return
+	}
+
+	hop := offset - p.previousOffset
+	hues := make([]float64, 0, len(spectra)*mapper.Bands())
+	for i, s := range spectra {
+		var previous []complex128
+		if i < len(p.previous) {
+			previous = p.previous[i]
+		}
+
+		for _, k := range mapper.PeakBins(s) {
+			hues = append(hues, p.hue(s, previous, k, hop))
+		}
+	}
+
+	p.hues = hues
+	p.previous = spectra
+	p.previousOffset = offset
+}
+
+// The hue of bin k of fftData, which started hop samples after previous.
+func (p phaseColors) hue(fftData, previous []complex128, k, hop int) float64 {
+	if k >= len(fftData) {
+		return 0
+	}
+
+	phase := cmplx.Phase(fftData[k])
+	if p.mode == PhaseChange {
+		if len(previous) != len(fftData) {
+			return 0
+		}
+		// A sine centred on bin k turns k times per window length.
+		expected := 2 * math.Pi * float64(k) * float64(hop) / float64(len(fftData))
+		phase -= cmplx.Phase(previous[k]) + expected
+	}
+
+	turns := math.Mod(phase/(2*math.Pi), 1)
+	if turns < 0 {
+		turns++
+	}
+
+	return turns
+}
+
+// color is the colour of a band, in the order passed to update.
+func (p phaseColors) color(band int) termenv.Color {
+	if band >= len(p.hues) {
+		return p.palette[0]
+	}
+
+	return p.palette[int(p.hues[band]*phaseShades)%phaseShades]
+}
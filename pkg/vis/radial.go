@@ -0,0 +1,263 @@
+package vis
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+
+	"github.com/brandonpollack23/goldsmith/pkg/spectrum"
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/muesli/termenv"
+)
+
+func init() {
+	Register("radial", "Bars radiating from a circle that pulses with the bass, drawn in braille",
+		slices.Concat([]OptionSpec{
+			{Name: "bars", Description: "Number of bars, doubled if mirrored", Default: "48"},
+			{Name: "size", Description: "Width in characters", Default: "80"},
+			{Name: "aspect", Description: "Height of a terminal cell over its width", Default: "2"},
+			{Name: "mirror", Description: "Mirror the bars on the left half, so the circle is symmetric", Default: "true"},
+			{Name: "pulse", Description: "How much the circle grows with the bass, 0 to keep it still", Default: "0.3"},
+		}, spectrumOptions, levelOptions),
+		func(cfg Config, opts ...VisualizerOption) (Visualizer, error) {
+			numBars, err := cfg.Int("bars")
+			if err != nil {
+				return nil, err
+			}
+			size, err := cfg.Int("size")
+			if err != nil {
+				return nil, err
+			}
+			aspect, err := cfg.Float("aspect")
+			if err != nil {
+				return nil, err
+			}
+			mirror, err := cfg.Bool("mirror")
+			if err != nil {
+				return nil, err
+			}
+			pulse, err := cfg.Float("pulse")
+			if err != nil {
+				return nil, err
+			}
+			if size < 1 {
+				return nil, fmt.Errorf("size must be at least 1, got %d", size)
+			}
+			if aspect <= 0 {
+				return nil, fmt.Errorf("aspect must be above 0, got %g", aspect)
+			}
+			if radialHeight(size, aspect) < 1 {
+				return nil, fmt.Errorf("size %d is less than a line tall with aspect %g", size, aspect)
+			}
+
+			mapper, err := spectrumMapperFromConfig(cfg, numBars)
+			if err != nil {
+				return nil, err
+			}
+			scale, err := levelScaleFromConfig(cfg)
+			if err != nil {
+				return nil, err
+			}
+
+			return NewRadialVisualizer(mapper, scale, size, aspect, mirror, pulse, opts...), nil
+		})
+}
+
+const (
+	// Bands below this drive the pulse.
+	bassCutoff = 150 // Hz
+	// Radius of the circle at rest, as a fraction of the largest that fits.
+	radialInnerRadius = 0.3
+)
+
+type RadialVisualizer struct {
+	VisualizerShared
+	program *tea.Program
+}
+
+func (v RadialVisualizer) UpdateVisualizer(newFFTData NewFFTData) {
+	v.program.Send(newFFTData)
+}
+
+type RadialModel struct {
+	GoldsmithSharedFields
+	mapper   *spectrum.Mapper
+	scale    *LevelScale
+	dynamics barDynamics
+
+	// Size in characters.
+	width  int
+	height int
+	// Height of a braille dot over its width.
+	dotAspect float64
+	mirror    bool
+	pulse     float64
+	// Number of bands below bassCutoff.
+	bassBands int
+
+	Color string
+}
+
+func NewRadialVisualizer(
+	mapper *spectrum.Mapper,
+	scale *LevelScale,
+	size int,
+	aspect float64,
+	mirror bool,
+	pulse float64,
+	opts ...VisualizerOption,
+) *RadialVisualizer {
+	// A cell is 2 dots wide and 4 tall.
+	dotAspect := aspect / 2
+
+	bassBands := 0
+	for _, edge := range mapper.Edges()[1:] {
+		if edge > bassCutoff {
+			break
+		}
+		bassBands++
+	}
+
+	m := RadialModel{
+		mapper:    mapper,
+		scale:     scale,
+		width:     size,
+		height:    radialHeight(size, aspect),
+		dotAspect: dotAspect,
+		mirror:    mirror,
+		pulse:     pulse,
+		// At least the lowest band, even if it reaches above the cutoff.
+		bassBands:             max(1, bassBands),
+		Color:                 "#7571F9",
+		GoldsmithSharedFields: initSharedFields(defaultKeymap),
+	}
+
+	p, doneChan := launchTeaProgram(&m, opts)
+
+	return &RadialVisualizer{
+		program:          p,
+		VisualizerShared: VisualizerShared{done: doneChan},
+	}
+}
+
+// Lines tall a circle size characters wide is, with cells aspect times taller
+// than they are wide.
+func radialHeight(size int, aspect float64) int {
+	return int(math.Round(float64(size) / aspect))
+}
+
+func (m *RadialModel) barDynamics() *barDynamics {
+	return &m.dynamics
+}
+
+func (m RadialModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m RadialModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case NewFFTData:
+		if msg.Done {
+			return m, tea.Quit
+		}
+
+		m.updateFPS()
+		m.dynamics.update(m.scale.Levels(m.mapper.Map(msg.Data), msg.Gain))
+		return m, nil
+
+	case tea.KeyMsg:
+		return m.handleKey(msg)
+
+	case tea.MouseMsg:
+		return m, m.handleMouse(msg)
+
+	case tea.WindowSizeMsg:
+		m.setWindowSize(msg)
+		return m, nil
+	}
+	return m, nil
+}
+
+func (m RadialModel) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case key.Matches(msg, m.keymap.quit):
+		return m, tea.Quit
+	}
+
+	return m, m.handlePlaybackKey(msg)
+}
+
+func (m RadialModel) View() string {
+	canvas := newBrailleCanvas(m.width, m.height)
+	levels, peaks := m.dynamics.levels, m.dynamics.peaks
+
+	// All distances are in dot widths, dots being dotAspect times taller.
+	cx := float64(canvas.dotWidth()-1) / 2
+	cy := float64(canvas.dotHeight()-1) / 2 * m.dotAspect
+	maxRadius := min(cx, cy)
+
+	var bass float64
+	if len(levels) > 0 {
+		for _, l := range levels[:min(m.bassBands, len(levels))] {
+			bass += l
+		}
+		bass /= float64(min(m.bassBands, len(levels)))
+	}
+	inner := maxRadius * radialInnerRadius * (1 + m.pulse*bass)
+	inner = min(inner, maxRadius)
+
+	plot := func(angle, r float64) {
+		x := cx + r*math.Sin(angle)
+		y := (cy - r*math.Cos(angle)) / m.dotAspect
+		canvas.set(int(math.Round(x)), int(math.Round(y)))
+	}
+
+	// The circle, a dot per dot width of its circumference.
+	steps := max(1, int(2*math.Pi*inner))
+	for i := range steps {
+		plot(2*math.Pi*float64(i)/float64(steps), inner)
+	}
+
+	// Bars clockwise from the top, low frequencies first.
+	spokes := len(levels)
+	if m.mirror {
+		spokes *= 2
+	}
+	showPeaks := m.dynamics.showPeaks()
+	for i := range spokes {
+		band := i
+		if band >= len(levels) {
+			// Mirrored, back up the left side.
+			band = spokes - 1 - i
+		}
+
+		angle := 2 * math.Pi * float64(i) / float64(spokes)
+		if m.mirror {
+			// Centre the first bar on the top so the halves meet there.
+			angle = 2 * math.Pi * (float64(i) + 0.5) / float64(spokes)
+		}
+
+		length := levels[band] * (maxRadius - inner)
+		for r := inner; r <= inner+length; r += 0.5 {
+			plot(angle, r)
+		}
+		if showPeaks {
+			plot(angle, inner+peaks[band].level*(maxRadius-inner))
+		}
+	}
+
+	var b strings.Builder
+	color := termenv.ColorProfile().Color(m.Color)
+	for _, row := range canvas.rows() {
+		b.WriteString(termenv.String(row).Foreground(color).String())
+		b.WriteRune('\n')
+	}
+
+	if m.showFPS {
+		displayFPS(&b, m.GoldsmithSharedFields)
+	}
+
+	return m.withFooter(b.String())
+}
@@ -0,0 +1,213 @@
+package vis
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gopxl/beep"
+)
+
+// OptionSpec describes one visualizer specific option.
+type OptionSpec struct {
+	Name        string
+	Description string
+	Default     string
+}
+
+// Config is what a registered visualizer is built from.
+type Config struct {
+	// Format of the audio being visualized.
+	Format beep.Format
+	// Values for every option in the visualizer's schema, defaults filled in.
+	Options map[string]string
+}
+
+func (c Config) Int(name string) (int, error) {
+	v, err := strconv.Atoi(c.Options[name])
+	if err != nil {
+		return 0, fmt.Errorf("option %s: %w", name, err)
+	}
+
+	return v, nil
+}
+
+// Auto is the value of size options that follow the terminal size.
+const Auto = "auto"
+
+// IntOrAuto is like Int, but returns 0 for [Auto].
+func (c Config) IntOrAuto(name string) (int, error) {
+	if c.Options[name] == Auto {
+		return 0, nil
+	}
+
+	return c.Int(name)
+}
+
+func (c Config) Float(name string) (float64, error) {
+	v, err := strconv.ParseFloat(c.Options[name], 64)
+	if err != nil {
+		return 0, fmt.Errorf("option %s: %w", name, err)
+	}
+
+	return v, nil
+}
+
+func (c Config) Bool(name string) (bool, error) {
+	v, err := strconv.ParseBool(c.Options[name])
+	if err != nil {
+		return false, fmt.Errorf("option %s: %w", name, err)
+	}
+
+	return v, nil
+}
+
+func (c Config) Duration(name string) (time.Duration, error) {
+	v, err := time.ParseDuration(c.Options[name])
+	if err != nil {
+		return 0, fmt.Errorf("option %s: %w", name, err)
+	}
+
+	return v, nil
+}
+
+func (c Config) String(name string) string {
+	return c.Options[name]
+}
+
+// Constructor builds a visualizer from its config and the shared options.
+type Constructor func(cfg Config, opts ...VisualizerOption) (Visualizer, error)
+
+// Registration is a visualizer available through [New].
+type Registration struct {
+	Name        string
+	Description string
+	Options     []OptionSpec
+	New         Constructor
+	// Whether the visualizer reads the per-channel and side spectra of
+	// [NewFFTData], which cost three more FFTs per window. Nil for never.
+	ChannelSpectra func(Config) bool
+}
+
+type RegistrationOption func(*Registration)
+
+// UsesChannelSpectra tells [NeedsChannelSpectra] when the visualizer reads the
+// per-channel and side spectra.
+func UsesChannelSpectra(uses func(Config) bool) RegistrationOption {
+	return func(r *Registration) {
+		r.ChannelSpectra = uses
+	}
+}
+
+var (
+	registryMu sync.Mutex
+	registry   = map[string]Registration{}
+)
+
+// Register makes a visualizer available to [New] under name.
+func Register(
+	name, description string,
+	options []OptionSpec,
+	constructor Constructor,
+	regOpts ...RegistrationOption,
+) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if _, ok := registry[name]; ok {
+		panic("visualizer registered twice: " + name)
+	}
+
+	r := Registration{
+		Name:        name,
+		Description: description,
+		Options:     options,
+		New:         constructor,
+	}
+	for _, opt := range regOpts {
+		opt(&r)
+	}
+	registry[name] = r
+}
+
+// Registered lists all registered visualizers sorted by name.
+func Registered() []Registration {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	regs := make([]Registration, 0, len(registry))
+	for _, r := range registry {
+		regs = append(regs, r)
+	}
+	slices.SortFunc(regs, func(a, b Registration) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	return regs
+}
+
+// Names of all registered visualizers, sorted.
+func Names() []string {
+	regs := Registered()
+	names := make([]string, len(regs))
+	for i, r := range regs {
+		names[i] = r.Name
+	}
+
+	return names
+}
+
+// New builds the visualizer registered as name. Options not in its schema are
+// an error, and missing ones take their defaults.
+func New(
+	name string,
+	format beep.Format,
+	options map[string]string,
+	opts ...VisualizerOption,
+) (Visualizer, error) {
+	r, cfg, err := lookup(name, format, options)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.New(cfg, opts...)
+}
+
+// NeedsChannelSpectra reports whether the visualizer [New] would build from
+// the same arguments reads the per-channel and side spectra, so they are only
+// computed when drawn.
+func NeedsChannelSpectra(name string, format beep.Format, options map[string]string) (bool, error) {
+	r, cfg, err := lookup(name, format, options)
+	if err != nil {
+		return false, err
+	}
+
+	return r.ChannelSpectra != nil && r.ChannelSpectra(cfg), nil
+}
+
+// Finds the visualizer registered as name and fills in its config.
+func lookup(name string, format beep.Format, options map[string]string) (Registration, Config, error) {
+	registryMu.Lock()
+	r, ok := registry[name]
+	registryMu.Unlock()
+	if !ok {
+		return Registration{}, Config{}, fmt.Errorf("unknown visualizer %q, available visualizers are: %s",
+			name, strings.Join(Names(), ", "))
+	}
+
+	cfg := Config{Format: format, Options: map[string]string{}}
+	for _, o := range r.Options {
+		cfg.Options[o.Name] = o.Default
+	}
+	for k, v := range options {
+		if !slices.ContainsFunc(r.Options, func(o OptionSpec) bool { return o.Name == k }) {
+			return Registration{}, Config{}, fmt.Errorf("visualizer %s has no option %q", name, k)
+		}
+		cfg.Options[k] = v
+	}
+
+	return r, cfg, nil
+}
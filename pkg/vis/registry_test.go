@@ -0,0 +1,67 @@
+package vis
+
+import (
+	"testing"
+
+	"github.com/gopxl/beep"
+)
+
+func TestNeedsChannelSpectra(t *testing.T) {
+	format := beep.Format{SampleRate: 44100, NumChannels: 2, Precision: 2}
+	tests := []struct {
+		name    string
+		options map[string]string
+		want    bool
+	}{
+		{"vertical_bars", nil, false},
+		{"vertical_bars", map[string]string{"layout": "stereo"}, true},
+		{"vertical_bars", map[string]string{"layout": "mid_side"}, true},
+		{"horizontal_bars", nil, false},
+		{"spectrogram", nil, false},
+	}
+
+	for _, tt := range tests {
+		got, err := NeedsChannelSpectra(tt.name, format, tt.options)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("NeedsChannelSpectra(%s, %v) = %v, want %v", tt.name, tt.options, got, tt.want)
+		}
+	}
+
+	if _, err := NeedsChannelSpectra("nope", format, nil); err == nil {
+		t.Error("no error for an unknown visualizer")
+	}
+	if _, err := NeedsChannelSpectra("vertical_bars", format, map[string]string{"nope": "1"}); err == nil {
+		t.Error("no error for an unknown option")
+	}
+}
+
+// Sizes that would leave nothing to draw are rejected before anything is.
+func TestOptionValidation(t *testing.T) {
+	format := beep.Format{SampleRate: 44100, NumChannels: 2, Precision: 2}
+	tests := []struct {
+		name    string
+		options map[string]string
+	}{
+		{"spectrogram", map[string]string{"width": "0"}},
+		{"spectrogram", map[string]string{"height": "-1"}},
+		{"oscilloscope", map[string]string{"width": "0"}},
+		{"oscilloscope", map[string]string{"height": "0"}},
+		{"goniometer", map[string]string{"size": "1"}},
+		{"radial", map[string]string{"size": "0"}},
+		{"radial", map[string]string{"aspect": "0"}},
+		{"radial", map[string]string{"aspect": "-2"}},
+		// Rounds to no lines at all.
+		{"radial", map[string]string{"size": "1", "aspect": "4"}},
+		{"meters", map[string]string{"width": "0"}},
+		{"meters", map[string]string{"floor": "0"}},
+	}
+
+	for _, tt := range tests {
+		if _, err := New(tt.name, format, tt.options); err == nil {
+			t.Errorf("New(%s, %v) accepted invalid options", tt.name, tt.options)
+		}
+	}
+}
@@ -0,0 +1,229 @@
+package vis
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+
+	"github.com/brandonpollack23/goldsmith/pkg/spectrum"
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/muesli/termenv"
+)
+
+func init() {
+	Register("spectrogram", "A waterfall of spectra scrolling right to left, low frequencies at the bottom",
+		slices.Concat([]OptionSpec{
+			{Name: "width", Description: "Number of windows shown, one per column", Default: "100"},
+			{Name: "height", Description: "Height in lines, each showing two bands", Default: "30"},
+			{Name: "colormap", Description: "Colour map, one of " + strings.Join(colorMapNames(), ", "), Default: "viridis"},
+		}, spectrumOptions, levelOptions),
+		func(cfg Config, opts ...VisualizerOption) (Visualizer, error) {
+			width, err := cfg.Int("width")
+			if err != nil {
+				return nil, err
+			}
+			height, err := cfg.Int("height")
+			if err != nil {
+				return nil, err
+			}
+			if width < 1 || height < 1 {
+				return nil, fmt.Errorf("width and height must be at least 1, got %dx%d", width, height)
+			}
+			colors, err := lookupColorMap(cfg.String("colormap"))
+			if err != nil {
+				return nil, err
+			}
+
+			mapper, err := spectrumMapperFromConfig(cfg, 2*height)
+			if err != nil {
+				return nil, err
+			}
+			scale, err := levelScaleFromConfig(cfg)
+			if err != nil {
+				return nil, err
+			}
+
+			return NewSpectrogramVisualizer(mapper, scale, width, colors, opts...), nil
+		})
+}
+
+// Number of colours each colour map is sampled at.
+const spectrogramShades = 64
+
+// Width of the frequency labels left of the spectrogram.
+const frequencyLabelWidth = 5
+
+type SpectrogramVisualizer struct {
+	VisualizerShared
+	program *tea.Program
+}
+
+func (v SpectrogramVisualizer) UpdateVisualizer(newFFTData NewFFTData) {
+	v.program.Send(newFFTData)
+}
+
+type SpectrogramModel struct {
+	GoldsmithSharedFields
+	mapper *spectrum.Mapper
+	scale  *LevelScale
+
+	// Levels of each band of the most recent windows, oldest first.
+	columns [][]float64
+	width   int
+
+	palette []termenv.Color
+	// Frequency label of each line, top first, blank for most.
+	labels []string
+}
+
+func NewSpectrogramVisualizer(
+	mapper *spectrum.Mapper,
+	scale *LevelScale,
+	width int,
+	colors colorMap,
+	opts ...VisualizerOption,
+) *SpectrogramVisualizer {
+	m := SpectrogramModel{
+		mapper:                mapper,
+		scale:                 scale,
+		width:                 width,
+		palette:               colors.palette(spectrogramShades),
+		labels:                frequencyLabels(mapper.Edges(), 2),
+		GoldsmithSharedFields: initSharedFields(defaultKeymap),
+	}
+
+	p, doneChan := launchTeaProgram(&m, opts)
+
+	return &SpectrogramVisualizer{
+		program:          p,
+		VisualizerShared: VisualizerShared{done: doneChan},
+	}
+}
+
+func (m SpectrogramModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m SpectrogramModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case NewFFTData:
+		if msg.Done {
+			return m, tea.Quit
+		}
+
+		m.updateFPS()
+		levels := m.scale.Levels(m.mapper.Map(msg.Data), msg.Gain)
+		if len(m.columns) >= m.width {
+			m.columns = slices.Clone(m.columns[len(m.columns)-m.width+1:])
+		}
+		m.columns = append(m.columns, levels)
+		return m, nil
+
+	case tea.KeyMsg:
+		return m.handleKey(msg)
+
+	case tea.MouseMsg:
+		return m, m.handleMouse(msg)
+
+	case tea.WindowSizeMsg:
+		m.setWindowSize(msg)
+		return m, nil
+	}
+	return m, nil
+}
+
+func (m SpectrogramModel) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case key.Matches(msg, m.keymap.quit):
+		return m, tea.Quit
+	}
+
+	return m, m.handlePlaybackKey(msg)
+}
+
+func (m SpectrogramModel) View() string {
+	var b strings.Builder
+
+	// Columns not filled yet are blank, so the newest is always on the right.
+	blank := strings.Repeat(" ", m.width-len(m.columns))
+	for line, label := range m.labels {
+		fmt.Fprintf(&b, "%*s ", frequencyLabelWidth, label)
+		b.WriteString(blank)
+
+		// Each line is two bands, the upper drawn in the foreground of a
+		// half block and the lower in its background.
+		upper := 2*(len(m.labels)-line) - 1
+		lower := upper - 1
+		for _, column := range m.columns {
+			if upper >= len(column) {
+				// An odd number of bands leaves the top line half empty.
+				b.WriteString(termenv.String("▄").Foreground(m.shade(column, lower)).String())
+				continue
+			}
+			b.WriteString(termenv.String("▀").
+				Foreground(m.shade(column, upper)).
+				Background(m.shade(column, lower)).
+				String())
+		}
+		b.WriteRune('\n')
+	}
+
+	if m.showFPS {
+		displayFPS(&b, m.GoldsmithSharedFields)
+	}
+
+	return m.withFooter(b.String())
+}
+
+func (m SpectrogramModel) shade(levels []float64, band int) termenv.Color {
+	i := int(math.Round(levels[band] * float64(len(m.palette)-1)))
+	return m.palette[i]
+}
+
+// Labels for the lines of a display with bandsPerLine bands per line, top
+// first, for round frequencies that fall in them. Lines next to an already
+// labelled one are left blank so labels stay readable.
+func frequencyLabels(edges []float64, bandsPerLine int) []string {
+	bands := len(edges) - 1
+	lines := (bands + bandsPerLine - 1) / bandsPerLine
+	labels := make([]string, lines)
+
+	for _, f := range roundFrequencies(edges[0], edges[bands]) {
+		band, _ := slices.BinarySearch(edges, f)
+		band = max(0, min(bands-1, band-1))
+		line := lines - 1 - band/bandsPerLine
+
+		if labels[line] != "" ||
+			(line > 0 && labels[line-1] != "") ||
+			(line < lines-1 && labels[line+1] != "") {
+			continue
+		}
+		labels[line] = formatFrequency(f)
+	}
+
+	return labels
+}
+
+// 1, 2 and 5 times each power of ten between lo and hi.
+func roundFrequencies(lo, hi float64) []float64 {
+	var fs []float64
+	for decade := math.Pow(10, math.Floor(math.Log10(lo))); decade <= hi; decade *= 10 {
+		for _, m := range []float64{1, 2, 5} {
+			if f := m * decade; f >= lo && f <= hi {
+				fs = append(fs, f)
+			}
+		}
+	}
+
+	return fs
+}
+
+func formatFrequency(f float64) string {
+	if f >= 1000 {
+		return fmt.Sprintf("%gk", f/1000)
+	}
+
+	return fmt.Sprintf("%g", f)
+}
@@ -0,0 +1,196 @@
+package vis
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/brandonpollack23/goldsmith/pkg/spectrum"
+)
+
+// Options shared by every visualizer drawing frequency bands.
+var spectrumOptions = []OptionSpec{
+	{
+		Name:        "scale",
+		Description: "Frequency scale, one of " + strings.Join(spectrum.Scales(), ", "),
+		Default:     string(spectrum.Log),
+	},
+	{Name: "min_freq", Description: "Lowest frequency shown in Hz", Default: fmt.Sprint(spectrum.DefaultMinFrequency)},
+	{Name: "max_freq", Description: "Highest frequency shown in Hz", Default: fmt.Sprint(spectrum.DefaultMaxFrequency)},
+	{Name: "interpolate", Description: "Interpolate bands narrower than one FFT bin", Default: "true"},
+}
+
+// BandMapper builds a mapper onto the given number of bands, for visualizers
+// that change how many bands they draw as the terminal is resized.
+type BandMapper func(bands int) (*spectrum.Mapper, error)
+
+// bandMapperFromConfig builds mappers from the options in [spectrumOptions].
+func bandMapperFromConfig(cfg Config) BandMapper {
+	return func(bands int) (*spectrum.Mapper, error) {
+		return spectrumMapperFromConfig(cfg, bands)
+	}
+}
+
+// resizableMapper is a mapper whose number of bands can be changed.
+type resizableMapper struct {
+	*spectrum.Mapper
+	newMapper BandMapper
+	// The number of bands asked for, which for some scales is not the number
+	// the mapper has.
+	bands int
+}
+
+func newResizableMapper(newMapper BandMapper, bands int) (resizableMapper, error) {
+	mapper, err := newMapper(bands)
+	if err != nil {
+		return resizableMapper{}, err
+	}
+
+	return resizableMapper{Mapper: mapper, newMapper: newMapper, bands: bands}, nil
+}
+
+// resize rebuilds the mapper for a different number of bands. The options
+// were already checked building the first one, so should that somehow fail
+// the current mapper is kept.
+func (r *resizableMapper) resize(bands int) {
+	if bands == r.bands {
+		return
+	}
+
+	if mapper, err := r.newMapper(bands); err == nil {
+		r.Mapper = mapper
+		r.bands = bands
+	}
+}
+
+// spectrumMapperFromConfig builds a mapper onto bands bands from the options
+// in [spectrumOptions].
+func spectrumMapperFromConfig(cfg Config, bands int) (*spectrum.Mapper, error) {
+	scale, err := spectrum.ParseScale(cfg.String("scale"))
+	if err != nil {
+		return nil, err
+	}
+	minFreq, err := cfg.Float("min_freq")
+	if err != nil {
+		return nil, err
+	}
+	maxFreq, err := cfg.Float("max_freq")
+	if err != nil {
+		return nil, err
+	}
+	interpolate, err := cfg.Bool("interpolate")
+	if err != nil {
+		return nil, err
+	}
+
+	return spectrum.NewMapper(bands, cfg.Format.SampleRate,
+		spectrum.WithScale(scale),
+		spectrum.WithFrequencyRange(minFreq, maxFreq),
+		spectrum.WithInterpolation(interpolate))
+}
+
+// Options shared by every visualizer drawing levels in dB.
+var levelOptions = []OptionSpec{
+	{Name: "floor", Description: "Level in dBFS drawn as empty", Default: "-90"},
+	{Name: "ceiling", Description: "Level in dBFS drawn as full", Default: "0"},
+	{Name: "auto_gain", Description: "Slowly lower the ceiling to the loudest recent level", Default: "false"},
+}
+
+const (
+	// How fast the auto gain ceiling falls back towards quieter audio.
+	autoGainRelease = 3 // dB per second
+	// The auto gain ceiling never comes closer than this to the floor.
+	autoGainMinRange = 30 // dB
+)
+
+// LevelScale turns band magnitudes into dBFS, then into the fraction of the
+// floor to ceiling range they fill.
+type LevelScale struct {
+	floor    float64
+	ceiling  float64
+	autoGain bool
+
+	// Current auto gain ceiling and when it was last moved.
+	gainCeiling float64
+	lastUpdate  time.Time
+}
+
+// levelScaleFromConfig builds a level scale from the options in
+// [levelOptions].
+func levelScaleFromConfig(cfg Config) (*LevelScale, error) {
+	floor, err := cfg.Float("floor")
+	if err != nil {
+		return nil, err
+	}
+	ceiling, err := cfg.Float("ceiling")
+	if err != nil {
+		return nil, err
+	}
+	autoGain, err := cfg.Bool("auto_gain")
+	if err != nil {
+		return nil, err
+	}
+
+	return NewLevelScale(floor, ceiling, autoGain)
+}
+
+// NewLevelScale draws floor dBFS as empty and ceiling dBFS as full. With
+// autoGain the ceiling follows the loudest recent level down, so quiet audio
+// still fills the screen, and back up as soon as anything louder plays.
+func NewLevelScale(floor, ceiling float64, autoGain bool) (*LevelScale, error) {
+	if floor >= ceiling {
+		return nil, fmt.Errorf("floor %g dB must be below ceiling %g dB", floor, ceiling)
+	}
+
+	return &LevelScale{
+		floor:       floor,
+		ceiling:     ceiling,
+		autoGain:    autoGain,
+		gainCeiling: ceiling,
+	}, nil
+}
+
+// Levels converts magnitudes from an FFT window with the given gain, see
+// [NewFFTData], to fractions between 0 and 1. With auto gain on this also
+// moves the ceiling, so call it once per window.
+func (s *LevelScale) Levels(magnitudes []float64, gain float64) []float64 {
+	if gain <= 0 {
+		gain = 1
+	}
+
+	loudest := math.Inf(-1)
+	dbs := make([]float64, len(magnitudes))
+	for i, m := range magnitudes {
+		dbs[i] = 20 * math.Log10(m/gain)
+		loudest = max(loudest, dbs[i])
+	}
+
+	ceiling := s.ceiling
+	if s.autoGain {
+		ceiling = s.updateGainCeiling(loudest)
+	}
+
+	levels := make([]float64, len(dbs))
+	for i, db := range dbs {
+		// Silence is -Inf dB, which clamps to the floor like anything else.
+		levels[i] = max(0, min(1, (db-s.floor)/(ceiling-s.floor)))
+	}
+
+	return levels
+}
+
+// The ceiling jumps up to anything louder right away, so nothing clips, and
+// drifts down at autoGainRelease otherwise.
+func (s *LevelScale) updateGainCeiling(loudest float64) float64 {
+	now := time.Now()
+	if !s.lastUpdate.IsZero() {
+		s.gainCeiling -= autoGainRelease * now.Sub(s.lastUpdate).Seconds()
+	}
+	s.lastUpdate = now
+
+	s.gainCeiling = max(s.gainCeiling, loudest, s.floor+autoGainMinRange)
+	s.gainCeiling = min(s.gainCeiling, s.ceiling)
+
+	return s.gainCeiling
+}
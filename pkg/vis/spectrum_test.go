@@ -0,0 +1,53 @@
+package vis
+
+import (
+	"math"
+	"testing"
+
+	"github.com/brandonpollack23/goldsmith/pkg/fft"
+	"github.com/brandonpollack23/goldsmith/pkg/spectrum"
+	dspfft "github.com/mjibson/go-dsp/fft"
+)
+
+func TestFullScaleSineIsZeroDBFS(t *testing.T) {
+	const (
+		sampleRate = 44100
+		fftSize    = 2048
+		bin        = 64
+	)
+
+	mapper, err := spectrum.NewMapper(32, sampleRate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A ceiling above 0 dBFS, so levels reading high are not clamped.
+	const floor, ceiling = -90.0, 10.0
+	scale, err := NewLevelScale(floor, ceiling, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range fft.WindowFuncNames() {
+		t.Run(name, func(t *testing.T) {
+			windowFunc, _ := fft.LookupWindowFunc(name)
+			coefficients := windowFunc(fftSize)
+
+			// A sine centred on a bin, windowed the way the FFT streamer does.
+			var gain float64
+			samples := make([]float64, fftSize)
+			for i, c := range coefficients {
+				samples[i] = c * math.Sin(2*math.Pi*bin*float64(i)/fftSize)
+				gain += c
+			}
+
+			levels := scale.Levels(mapper.Map(dspfft.FFTReal(samples)), gain)
+			loudest := floor
+			for _, l := range levels {
+				loudest = max(loudest, floor+l*(ceiling-floor))
+			}
+			if math.Abs(loudest) > 0.01 {
+				t.Errorf("full scale sine reads %.2f dBFS, want 0", loudest)
+			}
+		})
+	}
+}
@@ -0,0 +1,227 @@
+package vis
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/muesli/termenv"
+)
+
+// Options shared by the bar visualizers for how they are coloured.
+var themeOptions = []OptionSpec{
+	{
+		Name: "theme",
+		Description: "Colour theme, one of " + strings.Join(themeNames(), ", ") +
+			", or a JSON theme file given by path or by name in " + filepath.Join("<config dir>", themeDir),
+		Default: "default",
+	},
+	{Name: "gradient", Description: "Run the theme's gradient along bar height or frequency", Default: string(HeightGradient)},
+	{
+		Name:        "phase",
+		Description: "Colour bars by phase instead: off, phase, or change for the phase change between windows",
+		Default:     string(NoPhase),
+	},
+}
+
+// Where user themes are looked up by name, under the user config directory.
+var themeDir = filepath.Join("goldsmith", "themes")
+
+// Number of colours a theme's gradient is sampled at.
+const themeShades = 32
+
+// Theme colours the bar visualizers. Themes are read from JSON files with the
+// same field names in lower case.
+type Theme struct {
+	// Colours of a full bar, from its bottom to its top or from the lowest
+	// band to the highest. One colour is a solid fill.
+	Gradient []string `json:"gradient"`
+	// Colour of the empty part of a bar.
+	Empty string `json:"empty"`
+	// Colour of peak caps.
+	Peak string `json:"peak"`
+}
+
+var themes = map[string]Theme{
+	"default": {
+		Gradient: []string{"#5A56E0", "#EE6FF8"},
+		Empty:    "#606060",
+		Peak:     "#F25D94",
+	},
+	"fire": {
+		Gradient: []string{"#5c0a00", "#b31b00", "#f04a00", "#ff9a00", "#ffe66d"},
+		Empty:    "#2b1a17",
+		Peak:     "#ffffff",
+	},
+	"ocean": {
+		Gradient: []string{"#03045e", "#0077b6", "#00b4d8", "#90e0ef", "#caf0f8"},
+		Empty:    "#1b2838",
+		Peak:     "#ffffff",
+	},
+	"monochrome": {
+		Gradient: []string{"#d0d0d0"},
+		Empty:    "#3a3a3a",
+		Peak:     "#ffffff",
+	},
+	// Solarized's accent colours over its dark background.
+	"solarized": {
+		Gradient: []string{"#268bd2", "#2aa198", "#859900", "#b58900", "#cb4b16", "#dc322f"},
+		Empty:    "#073642",
+		Peak:     "#fdf6e3",
+	},
+}
+
+// themeNames lists the built-in themes, sorted.
+func themeNames() []string {
+	names := make([]string, 0, len(themes))
+	for name := range themes {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	return names
+}
+
+// lookupTheme finds a built-in theme, or reads a theme file from a path or
+// from the user theme directory.
+func lookupTheme(name string) (Theme, error) {
+	if t, ok := themes[name]; ok {
+		return t, nil
+	}
+
+	if strings.ContainsRune(name, os.PathSeparator) || filepath.Ext(name) == ".json" {
+		return loadTheme(name)
+	}
+
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return Theme{}, fmt.Errorf("unknown theme %q, expected one of %s or a theme file",
+			name, strings.Join(themeNames(), ", "))
+	}
+	t, err := loadTheme(filepath.Join(configDir, themeDir, name+".json"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return Theme{}, fmt.Errorf("unknown theme %q, expected one of %s or a file in %s",
+			name, strings.Join(themeNames(), ", "), filepath.Join(configDir, themeDir))
+	}
+
+	return t, err
+}
+
+func loadTheme(path string) (Theme, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Theme{}, fmt.Errorf("reading theme: %w", err)
+	}
+
+	var t Theme
+	if err := json.Unmarshal(data, &t); err != nil {
+		return Theme{}, fmt.Errorf("parsing theme %s: %w", path, err)
+	}
+	if err := t.validate(); err != nil {
+		return Theme{}, fmt.Errorf("theme %s: %w", path, err)
+	}
+
+	return t, nil
+}
+
+func (t Theme) validate() error {
+	if len(t.Gradient) == 0 {
+		return errors.New("gradient needs at least one colour")
+	}
+	_, err := parseColorMap(slices.Concat(t.Gradient, []string{t.Empty, t.Peak})...)
+	return err
+}
+
+// GradientAxis is what a theme's gradient runs along.
+type GradientAxis string
+
+const (
+	// HeightGradient colours each cell of a bar by how far along the bar it
+	// is, so louder bands reach further up the gradient.
+	HeightGradient GradientAxis = "height"
+	// FrequencyGradient colours each bar by its band, low to high.
+	FrequencyGradient GradientAxis = "frequency"
+)
+
+func parseGradientAxis(name string) (GradientAxis, error) {
+	switch a := GradientAxis(name); a {
+	case HeightGradient, FrequencyGradient:
+		return a, nil
+	default:
+		return "", fmt.Errorf("unknown gradient %q, expected height or frequency", name)
+	}
+}
+
+// barTheme is a [Theme] converted to the colours the terminal supports. The
+// termenv profile picks the nearest of 256 or 16 colours where true colour is
+// not supported, and no colour at all on terminals without it, leaving the
+// bars to be told apart by their runes alone.
+type barTheme struct {
+	fill  []termenv.Color
+	empty termenv.Color
+	peak  termenv.Color
+	axis  GradientAxis
+}
+
+func newBarTheme(t Theme, axis GradientAxis) (barTheme, error) {
+	if err := t.validate(); err != nil {
+		return barTheme{}, err
+	}
+
+	profile := termenv.ColorProfile()
+	return barTheme{
+		fill:  mustColorMap(t.Gradient...).palette(themeShades),
+		empty: profile.Color(t.Empty),
+		peak:  profile.Color(t.Peak),
+		axis:  axis,
+	}, nil
+}
+
+// BarColors is how the bar visualizers are coloured.
+type BarColors struct {
+	Theme    Theme
+	Gradient GradientAxis
+	// Replaces the gradient unless [NoPhase].
+	Phase PhaseMode
+}
+
+// barColorsFromConfig reads the options in [themeOptions].
+func barColorsFromConfig(cfg Config) (BarColors, error) {
+	t, err := lookupTheme(cfg.String("theme"))
+	if err != nil {
+		return BarColors{}, err
+	}
+	axis, err := parseGradientAxis(cfg.String("gradient"))
+	if err != nil {
+		return BarColors{}, err
+	}
+	phase, err := parsePhaseMode(cfg.String("phase"))
+	if err != nil {
+		return BarColors{}, err
+	}
+
+	return BarColors{Theme: t, Gradient: axis, Phase: phase}, nil
+}
+
+// fillColor is the colour of the cell of band out of bands that is cell cells
+// along a bar length cells long.
+func (t barTheme) fillColor(band, bands, cell, length int) termenv.Color {
+	var pos float64
+	switch t.axis {
+	case FrequencyGradient:
+		if bands > 1 {
+			pos = float64(band) / float64(bands-1)
+		}
+	default:
+		if length > 1 {
+			pos = float64(cell) / float64(length-1)
+		}
+	}
+
+	return t.fill[int(pos*float64(len(t.fill)-1))]
+}
@@ -1,8 +1,7 @@
 package vis
 
 import (
-	"math"
-	"math/cmplx"
+	"fmt"
 	"slices"
 	"strings"
 
@@ -11,6 +10,76 @@ import (
 	"github.com/muesli/termenv"
 )
 
+func init() {
+	Register("vertical_bars", "One vertical bar per frequency band, low frequencies on the left",
+		slices.Concat([]OptionSpec{
+			{Name: "bars", Description: "Number of bars, or auto to fit the terminal width", Default: Auto},
+			{Name: "height", Description: "Height of the bars in lines, or auto to fit the terminal", Default: Auto},
+			{Name: "layout", Description: "mono, or stereo or mid_side mirrored around a centre line", Default: string(MonoLayout)},
+		}, spectrumOptions, levelOptions, themeOptions),
+		func(cfg Config, opts ...VisualizerOption) (Visualizer, error) {
+			numBars, err := cfg.IntOrAuto("bars")
+			if err != nil {
+				return nil, err
+			}
+			height, err := cfg.IntOrAuto("height")
+			if err != nil {
+				return nil, err
+			}
+			layout, err := parseBarLayout(cfg.String("layout"))
+			if err != nil {
+				return nil, err
+			}
+
+			scale, err := levelScaleFromConfig(cfg)
+			if err != nil {
+				return nil, err
+			}
+			colors, err := barColorsFromConfig(cfg)
+			if err != nil {
+				return nil, err
+			}
+
+			return NewVerticalBarsVisualizer(bandMapperFromConfig(cfg), scale, layout, numBars, height,
+				colors, opts...)
+		},
+		// Only the mirrored layouts draw more than the mid spectrum.
+		UsesChannelSpectra(func(cfg Config) bool {
+			return cfg.String("layout") != string(MonoLayout)
+		}))
+}
+
+// Used with bars=auto and height=auto until the terminal size is known.
+const (
+	defaultVerticalBars   = 64
+	defaultVerticalHeight = 40
+	// Width bars=auto aims for.
+	defaultBarWidth = 2
+)
+
+// BarLayout is how [VerticalBarsModel] arranges its bars.
+type BarLayout string
+
+const (
+	// MonoLayout draws the mid (mono) spectrum.
+	MonoLayout BarLayout = "mono"
+	// StereoLayout draws the left channel growing up from a centre line and
+	// the right channel growing down from it.
+	StereoLayout BarLayout = "stereo"
+	// MidSideLayout draws the mid signal growing up from a centre line and
+	// the side signal growing down from it.
+	MidSideLayout BarLayout = "mid_side"
+)
+
+func parseBarLayout(name string) (BarLayout, error) {
+	switch l := BarLayout(name); l {
+	case MonoLayout, StereoLayout, MidSideLayout:
+		return l, nil
+	default:
+		return "", fmt.Errorf("unknown layout %q, expected mono, stereo or mid_side", name)
+	}
+}
+
 type VerticalBarsVisualizer struct {
 	VisualizerShared
 	program *tea.Program
@@ -22,33 +91,72 @@ func (v VerticalBarsVisualizer) UpdateVisualizer(newFFTData NewFFTData) {
 
 type VerticalBarsModel struct {
 	GoldsmithSharedFields
-	fftData []complex128
+	mapper   resizableMapper
+	scale    *LevelScale
+	dynamics barDynamics
+	layout   BarLayout
+	// Bars and height asked for, 0 to fit the terminal.
 	numBars int
+	height  int
 	// Actual max bar height (as in character height)
 	maxBarHeight int
 	BarWidth     int
-	keymap       Keymap
 
 	TopDown bool
 
-	// TODO color ramp
-	Empty      rune
-	Full       rune
-	FullColor  string
-	EmptyColor string
+	theme barTheme
+	phase phaseColors
+	Empty rune
+	Full  rune
+	// PeakCap is drawn with bars growing up, TopDownPeakCap with them growing
+	// down.
+	PeakCap        rune
+	TopDownPeakCap rune
 }
 
-func NewVerticalBarsVisualizer(numBars int, maxBarHeight int, opts ...VisualizerOption) *VerticalBarsVisualizer {
+// NewVerticalBarsVisualizer draws numBars bars maxBarHeight lines tall. With
+// either 0 it is fit to the terminal instead, and the bars are widened to
+// fill it.
+func NewVerticalBarsVisualizer(
+	newMapper BandMapper,
+	scale *LevelScale,
+	layout BarLayout,
+	numBars int,
+	maxBarHeight int,
+	colors BarColors,
+	opts ...VisualizerOption,
+) (*VerticalBarsVisualizer, error) {
+	initialBars, initialHeight := numBars, maxBarHeight
+	if initialBars == 0 {
+		initialBars = defaultVerticalBars
+	}
+	if initialHeight == 0 {
+		initialHeight = defaultVerticalHeight
+	}
+	mapper, err := newResizableMapper(newMapper, initialBars)
+	if err != nil {
+		return nil, err
+	}
+	theme, err := newBarTheme(colors.Theme, colors.Gradient)
+	if err != nil {
+		return nil, err
+	}
+
 	m := VerticalBarsModel{
+		mapper:                mapper,
+		scale:                 scale,
+		layout:                layout,
 		numBars:               numBars,
-		keymap:                defaultKeymap,
+		height:                maxBarHeight,
 		TopDown:               false,
-		maxBarHeight:          maxBarHeight,
-		BarWidth:              2,
+		maxBarHeight:          initialHeight,
+		BarWidth:              defaultBarWidth,
+		theme:                 theme,
+		phase:                 newPhaseColors(colors.Phase),
 		Full:                  '█',
 		Empty:                 '░',
-		FullColor:             "#7571F9",
-		EmptyColor:            "#606060",
+		PeakCap:               '▁',
+		TopDownPeakCap:        '▔',
 		GoldsmithSharedFields: initSharedFields(defaultKeymap),
 	}
 
@@ -57,7 +165,11 @@ func NewVerticalBarsVisualizer(numBars int, maxBarHeight int, opts ...Visualizer
 	return &VerticalBarsVisualizer{
 		program:          p,
 		VisualizerShared: VisualizerShared{done: doneChan},
-	}
+	}, nil
+}
+
+func (m *VerticalBarsModel) barDynamics() *barDynamics {
+	return &m.dynamics
 }
 
 func (m VerticalBarsModel) Init() tea.Cmd {
@@ -72,66 +184,139 @@ func (m VerticalBarsModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 
 		m.GoldsmithSharedFields.updateFPS()
-		m.fftData = msg.Data
+		spectra := m.spectra(msg)
+		m.dynamics.update(m.barLevels(spectra, msg.Gain))
+		m.phase.update(m.mapper.Mapper, msg.Offset, spectra...)
 		return m, nil
 
 	case tea.KeyMsg:
 		return m.handleKey(msg)
+
+	case tea.MouseMsg:
+		return m, m.handleMouse(msg)
+
+	case tea.WindowSizeMsg:
+		m.setWindowSize(msg)
+		m.fitToWindow()
+		return m, nil
 	}
 	return m, nil
 }
 
+// The spectra the layout draws, one for mono and the upper then lower half
+// otherwise.
+func (m VerticalBarsModel) spectra(msg NewFFTData) [][]complex128 {
+	switch m.layout {
+	case StereoLayout:
+		// Mono audio has no channel spectra, both channels are the mid.
+		if msg.Left == nil || msg.Right == nil {
+			return [][]complex128{msg.Data, msg.Data}
+		}
+		return [][]complex128{msg.Left, msg.Right}
+	case MidSideLayout:
+		// Without a side spectrum the side is silent.
+		return [][]complex128{msg.Data, msg.Side}
+	default:
+		return [][]complex128{msg.Data}
+	}
+}
+
+// The levels of every bar, in the mirrored layouts those growing up followed
+// by those growing down.
+func (m VerticalBarsModel) barLevels(spectra [][]complex128, gain float64) []float64 {
+	var magnitudes []float64
+	for _, s := range spectra {
+		magnitudes = append(magnitudes, m.mapper.Map(s)...)
+	}
+
+	// One call for both halves, so they share a scale.
+	return m.scale.Levels(magnitudes, gain)
+}
+
+// Fits whichever of the number of bars and their height are not fixed to the
+// terminal, and widens the bars to fill its width.
+func (m *VerticalBarsModel) fitToWindow() {
+	width, height := m.bodySize()
+	if width == 0 || height == 0 {
+		return
+	}
+
+	if m.height == 0 {
+		m.maxBarHeight = height
+	}
+	if m.numBars == 0 {
+		// A gap of one column after each bar.
+		m.mapper.resize(max(1, width/(defaultBarWidth+1)))
+		// The mirrored layouts draw every band twice.
+		bars := m.mapper.Bands()
+		if m.layout != MonoLayout {
+			bars *= 2
+		}
+		m.dynamics.resize(bars)
+	}
+	m.BarWidth = max(1, width/m.mapper.Bands()-1)
+}
+
 func (m VerticalBarsModel) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	switch {
 	case key.Matches(msg, m.keymap.quit):
 		return m, tea.Quit
 	}
 
-	return m, nil
+	return m, m.handlePlaybackKey(msg)
 }
 
 func (m VerticalBarsModel) View() string {
-	aggregateBars := make([]float64, m.numBars)
-
-	// First aggregate bars together from fft window to match requested number of bars.
-	// And also convert to logarithmic scale.
-	// Divide by 2 to combine the negative frequency components.
-	barsToAggregate := len(m.fftData) / m.numBars / 2
-	for bi := range m.numBars {
-		for i := range barsToAggregate {
-			posComponent := cmplx.Abs(m.fftData[bi*barsToAggregate+i])
-			negComponent := cmplx.Abs(m.fftData[len(m.fftData)-1-bi*barsToAggregate-i])
-			aggregateBars[bi] += posComponent + negComponent
-		}
-		aggregateBars[bi] = math.Log1p(aggregateBars[bi])
+	var b strings.Builder
+
+	levels, peaks := m.dynamics.levels, m.dynamics.peaks
+	if m.layout == MonoLayout {
+		m.writeBars(&b, levels, peaks, 0, m.maxBarHeight, m.TopDown)
+	} else {
+		// The first half of the bars grow up to the centre line, the second
+		// half down from it.
+		n := len(levels) / 2
+		half := m.maxBarHeight / 2
+		m.writeBars(&b, levels[:n], peaks[:n], 0, half, false)
+		m.writeBars(&b, levels[n:], peaks[n:], n, m.maxBarHeight-half, true)
 	}
-	maxComponent := slices.Max(aggregateBars)
-	for i := range m.numBars {
-		aggregateBars[i] /= maxComponent
+
+	if m.showFPS {
+		displayFPS(&b, m.GoldsmithSharedFields)
 	}
 
-	return m.verticalBarsView(aggregateBars)
+	return m.withFooter(b.String())
 }
 
-func (m VerticalBarsModel) verticalBarsView(aggregateBarPercents []float64) string {
+// Writes height rows of bars, growing down from the top if topDown and up
+// from the bottom otherwise. first is the index of the first bar among all of
+// them.
+func (m VerticalBarsModel) writeBars(b *strings.Builder, levels []float64, peaks []peak, first, height int, topDown bool) {
 	padding := " "
-	var b strings.Builder
+	showPeaks := m.dynamics.showPeaks()
 
-	for i := range m.maxBarHeight {
+	for i := range height {
 		row := i
-		if !m.TopDown {
-			row = m.maxBarHeight - i - 1
+		if !topDown {
+			row = height - i - 1
 		}
 
-		for _, p := range aggregateBarPercents {
-			barHeight := int(p * float64(m.maxBarHeight))
+		for bi, p := range levels {
+			barHeight := int(p * float64(height))
 			if row < barHeight {
 				// Solid fill
-				s := termenv.String(string(m.Full)).Foreground(m.color(m.FullColor)).String()
+				color := m.theme.fillColor(bi, len(levels), row, height)
+				if m.phase.enabled() {
+					color = m.phase.color(first + bi)
+				}
+				s := termenv.String(string(m.Full)).Foreground(color).String()
 				b.WriteString(strings.Repeat(s, m.BarWidth))
+			} else if showPeaks && row == int(peaks[bi].level*float64(height)) {
+				c := termenv.String(string(m.peakCap(topDown))).Foreground(m.theme.peak).String()
+				b.WriteString(strings.Repeat(c, m.BarWidth))
 			} else {
 				// Empty fill
-				e := termenv.String(string(m.Empty)).Foreground(m.color(m.EmptyColor)).String()
+				e := termenv.String(string(m.Empty)).Foreground(m.theme.empty).String()
 				b.WriteString(strings.Repeat(e, m.BarWidth))
 			}
 
@@ -139,14 +324,12 @@ func (m VerticalBarsModel) verticalBarsView(aggregateBarPercents []float64) stri
 		}
 		b.WriteRune('\n')
 	}
+}
 
-	if m.showFPS {
-		displayFPS(&b, m.GoldsmithSharedFields)
+func (m VerticalBarsModel) peakCap(topDown bool) rune {
+	if topDown {
+		return m.TopDownPeakCap
 	}
 
-	return b.String()
-}
-
-func (m VerticalBarsModel) color(c string) termenv.Color {
-	return termenv.ColorProfile().Color(c)
+	return m.PeakCap
 }
@@ -5,28 +5,60 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"strings"
 	"time"
 
+	"github.com/brandonpollack23/goldsmith/pkg/loudness"
 	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/progress"
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/ansi"
 )
 
 // TODO separate out shared and horiz bars.
 // TODO set the max number of bars equal to fftData window size.
 // TODO make vertical bars
-// TODO maybe use phase to determine color or width or something?
 
 // Shared visualizer information.
 
 var defaultKeymap = Keymap{
-	quit: key.NewBinding(key.WithKeys("q", "ctrl+c"), key.WithHelp("q", "quit")),
+	quit:         key.NewBinding(key.WithKeys("q", "ctrl+c"), key.WithHelp("q", "quit")),
+	playPause:    key.NewBinding(key.WithKeys(" "), key.WithHelp("space", "play/pause")),
+	seekBackward: key.NewBinding(key.WithKeys("left"), key.WithHelp("←", "back 5s")),
+	seekForward:  key.NewBinding(key.WithKeys("right"), key.WithHelp("→", "forward 5s")),
+	volumeUp:     key.NewBinding(key.WithKeys("+", "="), key.WithHelp("+", "volume up")),
+	volumeDown:   key.NewBinding(key.WithKeys("-"), key.WithHelp("-", "volume down")),
+	nextTrack:    key.NewBinding(key.WithKeys("n"), key.WithHelp("n", "next track")),
+	prevTrack:    key.NewBinding(key.WithKeys("p"), key.WithHelp("p", "previous track")),
 }
 
+const (
+	seekStep   = 5 * time.Second
+	volumeStep = 0.5
+)
+
 type Visualizer interface {
 	UpdateVisualizer(newFFTData NewFFTData)
 	Wait(context.Context) error
 }
 
+// Playback is whatever is feeding the visualizer audio, letting the
+// keybindings control it.
+type Playback interface {
+	TogglePause()
+	Seek(offset time.Duration) error
+	SeekTo(position time.Duration) error
+	AdjustVolume(delta float64)
+	NextTrack()
+	PreviousTrack()
+
+	Title() string
+	Seekable() bool
+	Position() time.Duration
+	// Zero when unknown, like for a stream.
+	Duration() time.Duration
+}
+
 type VisualizerShared struct {
 	done <-chan error
 }
@@ -45,11 +77,19 @@ type GoldsmithModel interface {
 	tea.Model
 	SetKeymap(k Keymap)
 	SetShowFPS(f bool)
+	SetPlayback(p Playback)
 }
 
 type GoldsmithSharedFields struct {
-	showFPS bool
-	keymap  Keymap
+	showFPS  bool
+	keymap   Keymap
+	playback Playback
+
+	// Terminal size, zero until the first tea.WindowSizeMsg.
+	width  int
+	height int
+	// Playback progress shown in the footer.
+	progress progress.Model
 
 	startTime     time.Time
 	lastFrameTime time.Time
@@ -61,6 +101,7 @@ func initSharedFields(keymap Keymap) GoldsmithSharedFields {
 	now := time.Now()
 	return GoldsmithSharedFields{
 		keymap:        keymap,
+		progress:      progress.New(progress.WithDefaultGradient(), progress.WithoutPercentage()),
 		startTime:     now,
 		lastFrameTime: now,
 	}
@@ -74,17 +115,41 @@ func (m *GoldsmithSharedFields) SetKeymap(k Keymap) {
 	m.keymap = k
 }
 
+func (m *GoldsmithSharedFields) SetPlayback(p Playback) {
+	m.playback = p
+}
+
 func (m GoldsmithSharedFields) AverageFPS() float64 {
 	return float64(m.frameCount) / (float64(time.Since(m.startTime).Seconds()))
 }
 
 type Keymap struct {
-	quit key.Binding
+	quit         key.Binding
+	playPause    key.Binding
+	seekBackward key.Binding
+	seekForward  key.Binding
+	volumeUp     key.Binding
+	volumeDown   key.Binding
+	nextTrack    key.Binding
+	prevTrack    key.Binding
 }
 
 type NewFFTData struct {
+	// Mid (mono) spectrum.
 	Data []complex128
-	Done bool
+	// Per channel and side spectra, nil for mono audio.
+	Left  []complex128
+	Right []complex128
+	Side  []complex128
+	// Time domain audio of the window.
+	Samples [][2]float64
+	// Levels measured up to the end of the window.
+	Levels loudness.Levels
+	// Magnitude of a full scale sine in Data, see [fft.FFTWindow].
+	Gain float64
+	// Offset of the window's first sample in the audio played.
+	Offset int
+	Done   bool
 }
 
 func (m *GoldsmithSharedFields) updateFPS() {
@@ -100,6 +165,145 @@ func (m *GoldsmithSharedFields) updateFPS() {
 	m.lastFrameTime = t
 }
 
+// Handles the playback keybindings shared by every visualizer.
+func (m GoldsmithSharedFields) handlePlaybackKey(msg tea.KeyMsg) tea.Cmd {
+	if m.playback == nil {
+		return nil
+	}
+
+	switch {
+	case key.Matches(msg, m.keymap.playPause):
+		m.playback.TogglePause()
+	case key.Matches(msg, m.keymap.seekBackward):
+		// Seeking past either end is clamped, so there is nothing worth reporting.
+		_ = m.playback.Seek(-seekStep)
+	case key.Matches(msg, m.keymap.seekForward):
+		_ = m.playback.Seek(seekStep)
+	case key.Matches(msg, m.keymap.volumeUp):
+		m.playback.AdjustVolume(volumeStep)
+	case key.Matches(msg, m.keymap.volumeDown):
+		m.playback.AdjustVolume(-volumeStep)
+	case key.Matches(msg, m.keymap.nextTrack):
+		m.playback.NextTrack()
+	case key.Matches(msg, m.keymap.prevTrack):
+		m.playback.PreviousTrack()
+	}
+
+	return nil
+}
+
+func (m *GoldsmithSharedFields) setWindowSize(msg tea.WindowSizeMsg) {
+	m.width = msg.Width
+	m.height = msg.Height
+}
+
+// bodySize is the room left for the visualizer itself once the footer and FPS
+// display have theirs, zero until the terminal size is known.
+func (m GoldsmithSharedFields) bodySize() (int, int) {
+	height := m.height
+	if m.playback != nil {
+		height -= footerHeight
+	}
+	if m.showFPS {
+		height -= fpsLines
+	}
+
+	return m.width, max(0, height)
+}
+
+// Clicking on the footer's progress bar seeks to that point of the track.
+func (m GoldsmithSharedFields) handleMouse(msg tea.MouseMsg) tea.Cmd {
+	if m.playback == nil || m.height == 0 || !m.playback.Seekable() {
+		return nil
+	}
+	if msg.Action != tea.MouseActionPress || msg.Button != tea.MouseButtonLeft {
+		return nil
+	}
+
+	barStart, barWidth := m.footerBarBounds()
+	if msg.Y != m.height-1 || msg.X < barStart || msg.X >= barStart+barWidth {
+		return nil
+	}
+
+	fraction := float64(msg.X-barStart) / float64(barWidth)
+	_ = m.playback.SeekTo(time.Duration(fraction * float64(m.playback.Duration())))
+
+	return nil
+}
+
+// Lines taken by the footer, the track title and the progress bar.
+const footerHeight = 2
+
+// Width of the timestamps on either side of the progress bar, enough for
+// "h:mm:ss" and a space.
+const timestampWidth = 8
+
+// Column the footer's progress bar starts at and how wide it is.
+func (m GoldsmithSharedFields) footerBarBounds() (int, int) {
+	width := m.width
+	if width == 0 {
+		width = m.progress.Width + 2*timestampWidth
+	}
+
+	return timestampWidth, max(0, width-2*timestampWidth)
+}
+
+// Appends the playback footer to the visualizer's body, pinned to the bottom
+// line of the terminal once its size is known.
+func (m GoldsmithSharedFields) withFooter(body string) string {
+	if m.playback == nil {
+		return body
+	}
+
+	var b strings.Builder
+	b.WriteString(body)
+
+	if m.height > 0 {
+		padding := m.height - footerHeight - strings.Count(body, "\n")
+		b.WriteString(strings.Repeat("\n", max(0, padding)))
+	}
+
+	title := m.playback.Title()
+	if m.width > 0 {
+		// By display width, since titles are often not ASCII.
+		title = ansi.Truncate(title, m.width, "")
+	}
+	fmt.Fprintf(&b, "%s\n", title)
+
+	position, duration := m.playback.Position(), m.playback.Duration()
+	if duration <= 0 {
+		// Nothing to show progress towards.
+		b.WriteString(formatTimestamp(position))
+		return b.String()
+	}
+	percent := float64(position) / float64(duration)
+
+	_, barWidth := m.footerBarBounds()
+	bar := m.progress
+	bar.Width = barWidth
+
+	fmt.Fprintf(&b, "%-*s%s%*s",
+		timestampWidth, formatTimestamp(position),
+		bar.ViewAs(percent),
+		timestampWidth, formatTimestamp(duration))
+
+	return b.String()
+}
+
+// Formats as m:ss, or h:mm:ss for anything an hour or longer.
+func formatTimestamp(d time.Duration) string {
+	d = d.Truncate(time.Second)
+	h, m, s := int(d.Hours()), int(d.Minutes())%60, int(d.Seconds())%60
+	if h > 0 {
+		return fmt.Sprintf("%d:%02d:%02d", h, m, s)
+	}
+
+	return fmt.Sprintf("%d:%02d", m, s)
+}
+
+// Lines written by displayFPS.
+const fpsLines = 3
+
 func displayFPS(b io.StringWriter, m GoldsmithSharedFields) error {
 	_, err := b.WriteString(fmt.Sprintf("Frame Count: %d\n", m.frameCount))
 	if err != nil {
@@ -135,6 +339,12 @@ func WithFPS(f bool) VisualizerOption {
 	}
 }
 
+func WithPlayback(p Playback) VisualizerOption {
+	return func(v GoldsmithModel) {
+		v.SetPlayback(p)
+	}
+}
+
 // Launches the bubble tea visualizer and returns the program handle as well as
 // a done signal channel.
 func launchTeaProgram(m GoldsmithModel, opts []VisualizerOption) (*tea.Program, <-chan error) {
@@ -142,7 +352,8 @@ func launchTeaProgram(m GoldsmithModel, opts []VisualizerOption) (*tea.Program,
 		opt(m)
 	}
 
-	p := tea.NewProgram(m, tea.WithoutSignalHandler())
+	// The alt screen gives the footer a fixed row for mouse seeking.
+	p := tea.NewProgram(m, tea.WithoutSignalHandler(), tea.WithAltScreen(), tea.WithMouseCellMotion())
 
 	waitChan := make(chan error)
 	go func() {
@@ -0,0 +1,57 @@
+package vis
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/charmbracelet/x/ansi"
+)
+
+// A stream of unknown length, so the footer is just the title and position.
+type fakePlayback struct {
+	title string
+}
+
+func (p fakePlayback) TogglePause()               {}
+func (p fakePlayback) Seek(time.Duration) error   { return nil }
+func (p fakePlayback) SeekTo(time.Duration) error { return nil }
+func (p fakePlayback) AdjustVolume(float64)       {}
+func (p fakePlayback) NextTrack()                 {}
+func (p fakePlayback) PreviousTrack()             {}
+func (p fakePlayback) Title() string              { return p.title }
+func (p fakePlayback) Seekable() bool             { return false }
+func (p fakePlayback) Position() time.Duration    { return 0 }
+func (p fakePlayback) Duration() time.Duration    { return 0 }
+
+func TestFooterTitleFitsWidth(t *testing.T) {
+	tests := []struct {
+		title string
+		width int
+		want  string
+	}{
+		{"Artist - Song", 20, "Artist - Song"},
+		{"Artist - Song", 6, "Artist"},
+		// Cut between characters, not inside one.
+		{"Björk - Jóga", 10, "Björk - Jó"},
+		// Wide characters take two columns, and one that would straddle the
+		// edge is left out.
+		{"坂本龍一 - 戦場のメリークリスマス", 7, "坂本龍"},
+	}
+
+	for _, tt := range tests {
+		m := initSharedFields(defaultKeymap)
+		m.SetPlayback(fakePlayback{title: tt.title})
+		m.width, m.height = tt.width, 10
+
+		footer := m.withFooter("")
+		title, _, _ := strings.Cut(strings.TrimLeft(footer, "\n"), "\n")
+		if title != tt.want {
+			t.Errorf("title %q in %d columns = %q, want %q", tt.title, tt.width, title, tt.want)
+		}
+		if !utf8.ValidString(title) || ansi.StringWidth(title) > tt.width {
+			t.Errorf("title %q is %d columns wide, more than %d", title, ansi.StringWidth(title), tt.width)
+		}
+	}
+}